@@ -0,0 +1,27 @@
+package tgmanager
+
+import (
+	"context"
+)
+
+// Actor is the user behind an update and the chat it came from.
+type Actor struct {
+	UserID       int64
+	Username     string
+	LanguageCode string
+	IsBot        bool
+	ChatType     ChatType
+	ThreadID     int64
+}
+
+type actorCtxKey struct{}
+
+// WithActor returns a copy of ctx carrying the actor, processors read it with ActorFromContext.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorCtxKey{}, actor)
+}
+
+func ActorFromContext(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(actorCtxKey{}).(Actor)
+	return actor, ok
+}
@@ -0,0 +1,138 @@
+package tgmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func Test_actor(t *testing.T) {
+	storage, sender := &testStorage{}, &testChatSender{}
+	manager, err := NewCallbackManager("default", CallBackAppearTypeUpdate, nil, storage, sender, nil, nil, nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	t.Cleanup(func() { _ = manager.Close(context.Background()) })
+	var seen []Actor
+	err = manager.AddProcessors(Processor{
+		Name: "start",
+		Processor: func(ctx context.Context, data InOutData) (InOutData, error) {
+			actor, _ := ActorFromContext(ctx)
+			seen = append(seen, actor)
+			data.AddNode(NewDefaultNode("next", "start", CallbackProcessorTypeProcess, nil))
+			return data, nil
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, ok := ActorFromContext(context.Background()); ok {
+		t.Error("context without actor must report none")
+	}
+
+	owner := Actor{UserID: 7, Username: "owner", ChatType: ChatTypeGroup, ThreadID: 3}
+	presser := Actor{UserID: 8, Username: "presser", ChatType: ChatTypeGroup, ThreadID: 3}
+	ctx := context.Background()
+	for _, step := range []struct {
+		name   string
+		action func() error
+		actor  Actor
+	}{
+		{name: "send node", actor: owner, action: func() error {
+			return manager.SendNode(WithActor(ctx, owner), NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "start")
+		}},
+		{name: "press", actor: presser, action: func() error {
+			return manager.ProcessCallback(ctx, presser, "", 1, 1, "start>0>0")
+		}},
+	} {
+		if err = step.action(); err != nil {
+			t.Fatal(step.name, "unexpected error:", err)
+		}
+		if actor := seen[len(seen)-1]; actor != step.actor {
+			t.Error(step.name, "processor actor non match:", actor)
+		}
+
+		var state inOutData
+		if err = json.Unmarshal(storage.states[1], &state); err != nil {
+			t.Fatal(step.name, "unexpected error:", err)
+		}
+		if state.Actor != step.actor || state.OwnerID != owner.UserID {
+			t.Error(step.name, "persisted actor non match:", state.Actor, state.OwnerID)
+		}
+		if threadID := sender.containers[len(sender.containers)-1].ThreadID; threadID != step.actor.ThreadID {
+			t.Error(step.name, "thread non match:", threadID)
+		}
+	}
+}
+
+func Test_ownerOnly(t *testing.T) {
+	reject := CallbackAnswer{Text: "Not your menu", ShowAlert: true}
+	owner, foreign := Actor{UserID: 7}, Actor{UserID: 8}
+	for _, tCase := range []struct {
+		name      string
+		ownerOnly bool
+		access    NodeAccess
+		sender    Actor
+		ownerID   int64
+		presser   Actor
+		err       error
+	}{
+		{name: "toggle off", sender: owner, presser: foreign},
+		{name: "owner", ownerOnly: true, sender: owner, presser: owner},
+		{name: "foreign user", ownerOnly: true, sender: owner, presser: foreign, err: ErrNotMenuOwner},
+		{name: "unknown presser", ownerOnly: true, sender: owner, presser: Actor{}, err: ErrNotMenuOwner},
+		{name: "unknown owner", ownerOnly: true, presser: owner, err: ErrNotMenuOwner},
+		{name: "owner from data", ownerOnly: true, ownerID: owner.UserID, presser: owner},
+		{name: "shared node", ownerOnly: true, access: NodeAccessShared, sender: owner, presser: foreign},
+		{name: "owner node", access: NodeAccessOwner, sender: owner, presser: foreign, err: ErrNotMenuOwner},
+		// nobody else can press in a private chat
+		{name: "private chat", ownerOnly: true, presser: Actor{UserID: 7, ChatType: ChatTypePrivate}},
+		{name: "private chat owner node", access: NodeAccessOwner, presser: Actor{UserID: 7, ChatType: ChatTypePrivate}},
+		{name: "group chat", ownerOnly: true, presser: Actor{UserID: 7, ChatType: ChatTypeGroup}, err: ErrNotMenuOwner},
+	} {
+		sender := &testChatSender{}
+		manager, err := NewCallbackManager("default", CallBackAppearTypeUpdate, nil, &testStorage{}, sender, nil, nil, nil)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		t.Cleanup(func() { _ = manager.Close(context.Background()) })
+		manager.SetOwnerOnly(tCase.ownerOnly, reject)
+		err = manager.AddProcessors(Processor{
+			Name: "start",
+			Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+				data.SetAccess(tCase.access)
+				data.AddNode(NewDefaultNode("next", "start", CallbackProcessorTypeProcess, nil))
+				return data, nil
+			},
+		})
+		if err != nil {
+			t.Fatal(tCase.name, "unexpected error:", err)
+		}
+
+		ctx := context.Background()
+		if tCase.sender != (Actor{}) {
+			ctx = WithActor(ctx, tCase.sender)
+		}
+		data := NewInOutData(1, 0, "hello", CallBackAppearTypeResend)
+		if tCase.ownerID != 0 {
+			data.SetOwnerID(tCase.ownerID)
+		}
+		if err = manager.SendNode(ctx, data, "start"); err != nil {
+			t.Fatal(tCase.name, "unexpected error:", err)
+		}
+
+		err = manager.ProcessCallback(context.Background(), tCase.presser, "query", 1, 1, "start>0>0")
+		if !errors.Is(err, tCase.err) {
+			t.Error(tCase.name, "error non match:", err)
+		}
+		expected := CallbackAnswer{}
+		if tCase.err != nil {
+			expected = reject
+		}
+		if len(sender.answers) != 1 || sender.answers[0] != expected {
+			t.Error(tCase.name, "answer non match:", sender.answers)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package tgmanager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// CallbackAnswer is shown by telegram clients after a button press: a toast, an alert or an url to open.
+// An empty answer just stops the button spinner.
+type CallbackAnswer struct {
+	Text      string
+	ShowAlert bool
+	URL       string
+	CacheTime int
+}
+
+// callbackAnswerer answers the callback query once, later calls are ignored.
+func (c *callbackManager) callbackAnswerer(ctx context.Context, queryID string) func(answer CallbackAnswer) {
+	var once sync.Once
+	return func(answer CallbackAnswer) {
+		once.Do(func() {
+			if err := c.sender.AnswerCallback(ctx, queryID, answer); err != nil {
+				c.logError(ctx, "answer callback", fmt.Errorf("answer callback: %w", err))
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package tgmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func Test_callbackAnswer(t *testing.T) {
+	toast := CallbackAnswer{Text: "Added to cart"}
+	errFailed := errors.New("failed")
+	for _, tCase := range []struct {
+		name     string
+		callback string
+		sendFail bool
+		answer   CallbackAnswer
+		err      error
+	}{
+		{name: "ignore", callback: CallbackProcessorTypeIgnore.String()},
+		{name: "invalid", callback: "add>0", err: ErrInvalidCallback},
+		{name: "processor error", callback: "fail>0>1", err: errFailed},
+		{name: "send error", callback: "add>0>0", sendFail: true, err: ErrSendFailed},
+		{name: "success", callback: "add>0>0", answer: toast},
+	} {
+		sender := &testChatSender{}
+		manager, err := NewCallbackManager("default", CallBackAppearTypeUpdate, nil, &testStorage{}, sender, nil, nil, nil)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		t.Cleanup(func() { _ = manager.Close(context.Background()) })
+		err = manager.AddProcessors(Processor{
+			Name: "start",
+			Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+				data.AddNode(NewDefaultNode("add", "add", CallbackProcessorTypeProcess, nil))
+				data.AddNode(NewDefaultNode("fail", "fail", CallbackProcessorTypeProcess, nil))
+				return data, nil
+			},
+		}, Processor{
+			Name: "add",
+			Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+				data.SetCallbackAnswer(toast)
+				return data, nil
+			},
+		}, Processor{
+			Name: "fail",
+			Processor: func(context.Context, InOutData) (InOutData, error) {
+				return nil, errFailed
+			},
+		})
+		if err != nil {
+			t.Fatal(tCase.name, "unexpected error:", err)
+		}
+
+		ctx := context.Background()
+		if err = manager.SendNode(ctx, NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "start"); err != nil {
+			t.Fatal(tCase.name, "unexpected error:", err)
+		}
+		sender.failChats = map[int64]bool{1: tCase.sendFail}
+
+		err = manager.ProcessCallback(ctx, Actor{UserID: 1}, "query", 1, 1, tCase.callback)
+		if !errors.Is(err, tCase.err) {
+			t.Error(tCase.name, "error non match:", err)
+		}
+		if len(sender.answers) != 1 || sender.answers[0] != tCase.answer {
+			t.Error(tCase.name, "expected exactly one answer", tCase.answer, "got", sender.answers)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package tgmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	backgroundWorkers     = 4
+	backgroundQueueSize   = 1024
+	backgroundTaskTimeout = 10 * time.Second
+	backgroundTaskRetries = 3
+	backgroundRetryDelay  = 500 * time.Millisecond
+)
+
+type backgroundTask struct {
+	ctx  context.Context
+	name string
+	run  func(ctx context.Context) error
+}
+
+// backgroundPool runs cleanup work after the request is answered. Tasks get a context detached
+// from the request cancellation with their own timeout and are retried on failure.
+type backgroundPool struct {
+	logger logger
+	tasks  chan backgroundTask
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newBackgroundPool(logger logger) *backgroundPool {
+	p := &backgroundPool{
+		logger: logger,
+		tasks:  make(chan backgroundTask, backgroundQueueSize),
+	}
+	p.wg.Add(backgroundWorkers)
+	for i := 0; i < backgroundWorkers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// submit queues the task without blocking the request, a task is dropped and logged when the queue is full.
+func (p *backgroundPool) submit(ctx context.Context, name string, run func(ctx context.Context) error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		p.logError(fmt.Errorf("%s: %w", name, ErrManagerClosed))
+		return
+	}
+	select {
+	case p.tasks <- backgroundTask{ctx: context.WithoutCancel(ctx), name: name, run: run}:
+	default:
+		p.logError(fmt.Errorf("%s dropped: background queue is full", name))
+	}
+}
+
+func (p *backgroundPool) work() {
+	defer p.wg.Done()
+	for task := range p.tasks {
+		p.execute(task)
+	}
+}
+
+func (p *backgroundPool) execute(task backgroundTask) {
+	var err error
+	for attempt := 0; attempt < backgroundTaskRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backgroundRetryDelay)
+		}
+		ctx, cancel := context.WithTimeout(task.ctx, backgroundTaskTimeout)
+		err = task.run(ctx)
+		cancel()
+		if err == nil {
+			return
+		}
+	}
+	p.logError(fmt.Errorf("%s failed after %d attempts: %w", task.name, backgroundTaskRetries, err))
+}
+
+// close stops accepting tasks and waits for the queued ones until ctx is done.
+func (p *backgroundPool) close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.tasks)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Join(errors.New("background tasks are not finished"), ctx.Err())
+	}
+}
+
+func (p *backgroundPool) logError(err error) {
+	if p.logger != nil {
+		p.logger.LogError(err)
+	}
+}
+
+// Close waits for pending cleanup of messages and states, the manager must not be used afterwards.
+func (c *callbackManager) Close(ctx context.Context) error {
+	return c.background.close(ctx)
+}
@@ -0,0 +1,73 @@
+package tgmanager
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testLogger struct {
+	errs atomic.Int64
+}
+
+func (l *testLogger) LogError(error) {
+	l.errs.Add(1)
+}
+
+func Test_backgroundPool(t *testing.T) {
+	log := &testLogger{}
+	pool := newBackgroundPool(log)
+
+	var attempts atomic.Int64
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pool.submit(ctx, "flaky", func(ctx context.Context) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if attempts.Add(1) == 1 {
+			return errors.New("temporary")
+		}
+		return nil
+	})
+
+	closeCtx, closeCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer closeCancel()
+	if err := pool.close(closeCtx); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if attempts.Load() != 2 {
+		t.Error("attempts non match", "actual:", attempts.Load(), "expected:", 2)
+	}
+	if log.errs.Load() != 0 {
+		t.Error("retried task must not be reported")
+	}
+
+	pool.submit(context.Background(), "late", func(context.Context) error { return nil })
+	if log.errs.Load() != 1 {
+		t.Error("task submitted after close must be reported")
+	}
+}
+
+func Test_backgroundPool_full(t *testing.T) {
+	log := &testLogger{}
+	pool := newBackgroundPool(log)
+
+	release := make(chan struct{})
+	for j := 0; j < backgroundWorkers+backgroundQueueSize+1; j++ {
+		pool.submit(context.Background(), "blocked", func(context.Context) error {
+			<-release
+			return nil
+		})
+	}
+	if log.errs.Load() == 0 {
+		t.Error("task submitted to the full queue must be dropped and reported")
+	}
+
+	close(release)
+	if err := pool.close(context.Background()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+}
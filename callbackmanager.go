@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
+	"time"
 )
 
 type CallbackNodeProcessorFunc func(ctx context.Context, data InOutData) (InOutData, error)
@@ -20,8 +22,10 @@ type storage interface {
 
 type telegramSender interface {
 	SendMsg(ctx context.Context, container TelegramContainer) (msgID int64, err error)
+	SendMediaGroup(ctx context.Context, chatID, threadID int64, items []*Media) (msgIDs []int64, err error)
 	DeleteMessage(messageID int64, chatID int64)
 	GetBotName() (string, error)
+	AnswerCallback(ctx context.Context, queryID string, answer CallbackAnswer) error
 }
 
 type logger interface {
@@ -30,11 +34,28 @@ type logger interface {
 
 type CallbackManager interface {
 	SendNode(ctx context.Context, data InOutData, processor string) error
-	ProcessCallback(ctx context.Context, oldMsgID, chatID int64, callback string) error
-	ProcessMsg(ctx context.Context, msgID, chatID int64, callback string) error
+	ProcessCallback(ctx context.Context, actor Actor, queryID string, oldMsgID, chatID int64, callback string) error
+	ProcessMsg(ctx context.Context, actor Actor, msgID, chatID int64, callback string) error
 	AddProcessors(items ...Processor) error
 	AddInlineProcessors(items ...InlineProcessor) error
 	GetProcessor(name string) CallbackNodeProcessorFunc
+	SetOwnerOnly(ownerOnly bool, rejectAnswer CallbackAnswer)
+	SetRoleResolver(resolver RoleResolverFunc)
+	SetRateLimit(config RateLimitConfig)
+	GetThrottleStats() ThrottleStats
+	SetConsistency(strategy ConsistencyStrategy, outbox OutboxStore) error
+	ReconcileOutbox(ctx context.Context) error
+	Close(ctx context.Context) error
+	SetSlogLogger(logger *slog.Logger)
+	SetErrorScreens(config ErrorScreenConfig)
+	SetProcessorTimeout(timeout time.Duration)
+	SetMetrics(metrics Metrics)
+	SetTracer(tracer Tracer)
+	SetEventSink(sink EventSink)
+	SetFlowRecording(enabled bool)
+	Crawl(ctx context.Context, entries ...string) error
+	FlowGraph() FlowGraph
+	Validate() error
 }
 type callbackManager struct {
 	defaultMsg                    string
@@ -51,11 +72,32 @@ type callbackManager struct {
 	inlineProcessorMap            map[string]SwitchInlineProcessorFunc
 	botName                       string
 	logger                        logger
+	ownerOnly                     bool
+	foreignUserAnswer             CallbackAnswer
+	processorRoles                map[string][]string
+	roleResolver                  RoleResolverFunc
+	rateLimiter                   *rateLimiter
+	consistency                   ConsistencyStrategy
+	outbox                        OutboxStore
+	background                    *backgroundPool
+	slogger                       *slog.Logger
+	errorScreens                  *ErrorScreenConfig
+	processorTimeout              time.Duration
+	processorTimeouts             map[string]time.Duration
+	inlineProcessorTimeouts       map[string]time.Duration
+	metrics                       Metrics
+	tracer                        Tracer
+	eventSink                     EventSink
+	flowRecording                 *flowRecorder
+	crawlRecording                *flowRecorder
+	processorLinks                map[string][]string
+	entryProcessors               map[string]struct{}
+	duplicateProcessors           map[string]struct{}
 }
 
 func (c *callbackManager) findAnyProcessorByName(name string) (CallbackNodeProcessorFunc, error) {
 	if val, ok := c.allProcessors[name]; !ok {
-		return nil, errors.New("processor not found")
+		return nil, &ManagerError{Op: "find processor", Processor: name, Err: ErrProcessorNotFound}
 	} else {
 		return val, nil
 	}
@@ -84,7 +126,7 @@ func NewCallbackManager(
 		return nil, errors.New("getting bot name")
 	}
 
-	return &callbackManager{
+	c := &callbackManager{
 		defaultMsg:        defaultMsg,
 		defaultAppearType: defaultAppearType,
 		defaultProcessor:  defaultProcessor,
@@ -97,17 +139,25 @@ func NewCallbackManager(
 		inlineProcessorMap:            make(map[string]SwitchInlineProcessorFunc),
 		botName:                       botName,
 		logger:                        logger,
-	}, nil
+		tracer:                        noopTracer{},
+	}
+	c.sender = &instrumentedSender{telegramSender: sender, c: c}
+	c.background = newBackgroundPool(logErrorFunc(func(err error) {
+		c.logError(context.Background(), "background task", err)
+	}))
+	return c, nil
 }
 
 type InlineProcessor struct {
 	Name      string
 	Processor SwitchInlineProcessorFunc
+	// Timeout overrides the default processor timeout, see SetProcessorTimeout
+	Timeout time.Duration
 }
 
 func (c *callbackManager) AddInlineProcessors(items ...InlineProcessor) error {
 	for i := range items {
-		if err := c.addInlineProcessor(items[i].Name, items[i].Processor); err != nil {
+		if err := c.addInlineProcessor(items[i].Name, items[i].Processor, items[i].Timeout); err != nil {
 			return err
 		}
 	}
@@ -121,42 +171,90 @@ func (c *callbackManager) GetProcessor(name string) CallbackNodeProcessorFunc {
 	return nil
 }
 
-func (c *callbackManager) addInlineProcessor(name string, processor SwitchInlineProcessorFunc) error {
+func (c *callbackManager) addInlineProcessor(name string, processor SwitchInlineProcessorFunc, timeout time.Duration) error {
 	if c.inlineProcessorMap == nil {
 		c.inlineProcessorMap = make(map[string]SwitchInlineProcessorFunc)
 	}
 	if _, ok := c.inlineProcessorMap[name]; ok {
+		c.addDuplicate(name)
 		return errors.New(fmt.Sprintf("duplicate processor: %s", name))
 	}
 	c.inlineProcessorMap[name] = processor
+	if timeout > 0 {
+		if c.inlineProcessorTimeouts == nil {
+			c.inlineProcessorTimeouts = make(map[string]time.Duration)
+		}
+		c.inlineProcessorTimeouts[name] = timeout
+	}
 	return nil
 }
 
 type Processor struct {
 	Name      string
 	Processor CallbackNodeProcessorFunc
+	// Roles limit the processor to users having any of them, empty means everyone
+	Roles []string
+	// Timeout overrides the default processor timeout, see SetProcessorTimeout
+	Timeout time.Duration
+	// Links are the processors the node's buttons lead to, they are checked by Validate
+	Links []string
+	// Entry marks processors sent with SendNode, Validate doesn't report them as orphans
+	Entry bool
 }
 
 func (c *callbackManager) AddProcessors(items ...Processor) error {
 	for i := range items {
-		if err := c.addProcessor(items[i].Name, items[i].Processor); err != nil {
+		if err := c.addProcessor(items[i].Name, items[i].Processor, items[i].Roles, items[i].Timeout); err != nil {
 			return err
 		}
+		if len(items[i].Links) != 0 {
+			if c.processorLinks == nil {
+				c.processorLinks = make(map[string][]string)
+			}
+			c.processorLinks[items[i].Name] = items[i].Links
+		}
+		if items[i].Entry {
+			if c.entryProcessors == nil {
+				c.entryProcessors = make(map[string]struct{})
+			}
+			c.entryProcessors[items[i].Name] = struct{}{}
+		}
 	}
 	return nil
 }
 
-func (c *callbackManager) addProcessor(name string, processor CallbackNodeProcessorFunc) error {
+func (c *callbackManager) addProcessor(name string, processor CallbackNodeProcessorFunc, roles []string, timeout time.Duration) error {
 	if c.allProcessors == nil {
 		c.allProcessors = make(map[string]CallbackNodeProcessorFunc)
 	}
 	if _, ok := c.allProcessors[name]; ok {
+		c.addDuplicate(name)
 		return errors.New(fmt.Sprintf("duplicate processor: %s", name))
 	}
 	c.allProcessors[name] = processor
+	if len(roles) != 0 {
+		if c.processorRoles == nil {
+			c.processorRoles = make(map[string][]string)
+		}
+		c.processorRoles[name] = roles
+	}
+	if timeout > 0 {
+		if c.processorTimeouts == nil {
+			c.processorTimeouts = make(map[string]time.Duration)
+		}
+		c.processorTimeouts[name] = timeout
+	}
 	return nil
 }
 
+// addDuplicate remembers a rejected registration for Validate.
+func (c *callbackManager) addDuplicate(name string) {
+	if c.duplicateProcessors == nil {
+		c.duplicateProcessors = make(map[string]struct{})
+	}
+	c.duplicateProcessors[name] = struct{}{}
+}
+
 func (c *callbackManager) SetDefaultProcessor(defaultProcessor CallbackNodeProcessorFunc) {
 	c.defaultProcessor = defaultProcessor
 }
@@ -169,47 +267,126 @@ func (c *callbackManager) SetCallbackDataNotFoundProcessor(callbackDataNotFoundP
 	c.callbackDataNotFoundProcessor = callbackDataNotFoundProcessor
 }
 
-func (c *callbackManager) SendNode(ctx context.Context, data InOutData, processor string) error {
+// SetOwnerOnly makes menus react only to the user who opened them, others get rejectAnswer.
+// Nodes can override it with InOutData.SetAccess. The owner is the actor of the SendNode context
+// unless InOutData.SetOwnerID sets it, menus without an owner reject every press. Private chats
+// have a single user and are never checked, messages added with InOutData.AddMessages are shared.
+func (c *callbackManager) SetOwnerOnly(ownerOnly bool, rejectAnswer CallbackAnswer) {
+	c.ownerOnly = ownerOnly
+	c.foreignUserAnswer = rejectAnswer
+}
+
+func (c *callbackManager) SendNode(ctx context.Context, data InOutData, processor string) (err error) {
+	start := time.Now()
+	ctx, span := c.startSpan(ctx, "tgmanager.SendNode", StringAttribute(AttrProcessor, processor))
+	defer func() {
+		if data != nil {
+			span.SetAttributes(
+				Int64Attribute(AttrChatID, data.GetChatID()),
+				Int64Attribute(AttrMessageID, data.getMsgID()),
+				StringAttribute(AttrAppearType, data.getAppearType().String()),
+			)
+		}
+		endSpan(span, err)
+	}()
+	defer func() {
+		attrs := []slog.Attr{slog.String(logKeyProcessor, processor)}
+		if data != nil {
+			attrs = append(attrs,
+				slog.Int64(logKeyChatID, data.GetChatID()),
+				slog.Int64(logKeyMessageID, data.getMsgID()),
+				slog.String(logKeyAppearType, data.getAppearType().String()),
+			)
+		}
+		c.logResult(ctx, "send node", start, err, attrs...)
+		c.countError("send_node", err)
+	}()
+
 	if data == nil {
-		return fmt.Errorf("data is required data")
+		return &ManagerError{Op: "send node", Processor: processor, Err: fmt.Errorf("%w: data is required", ErrInvalidData)}
 	}
+	defer func() {
+		err = wrapError("send node", processor, data.GetChatID(), data.getMsgID(), err)
+	}()
 
 	if data.getAppearType() != CallBackAppearTypeResend {
-		return fmt.Errorf("this method support only %s appear type", CallBackAppearTypeResend.String())
+		return fmt.Errorf("%w: this method support only %s appear type", ErrInvalidData, CallBackAppearTypeResend.String())
 	}
 
 	proc, ok := c.allProcessors[processor]
 	if !ok {
-		return errors.New("processor not found")
+		return ErrProcessorNotFound
+	}
+
+	event := TransitionEvent{To: processor, ChatID: data.GetChatID(), Time: start}
+	if actor, ok := ActorFromContext(ctx); ok {
+		event.UserID = actor.UserID
+	}
+	defer func() { c.emitTransition(ctx, event, err) }()
+
+	// sends on behalf of a user respect the user roles, the bot's own sends don't
+	if actor, ok := ActorFromContext(ctx); ok {
+		allowed, err := c.processorFilter(ctx, actor.UserID, data.GetChatID())
+		if err != nil {
+			return err
+		}
+		if allowed != nil && !allowed(processor) {
+			return ErrProcessorForbidden
+		}
 	}
 
-	newData, err := proc(ctx, data)
+	var newData InOutData
+	err = c.runProcessor(ctx, processor, c.callbackTimeout(processor), func(ctx context.Context) (err error) {
+		newData, err = proc(ctx, data)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("process: %w", err)
 	}
 	if newData == nil {
 		newData = data
 	}
+	data = newData
 	newData.setDefaultMessage(c.defaultMsg)
-
-	tgCont, err := newData.generateTelegramContainer()
-	if err != nil {
-		return fmt.Errorf("generate container: %w", err)
+	newData.setProcessor(processor)
+	if c.flowRecording != nil {
+		c.flowRecording.entry(processor)
 	}
-
-	newMsgID, err := c.sender.SendMsg(ctx, tgCont)
-	if err != nil {
-		return fmt.Errorf("sending tg msg: %w", err)
+	if actor, ok := ActorFromContext(ctx); ok {
+		newData.setActor(actor)
+		newData.setOwnerID(actor.UserID)
 	}
 
-	newData.setMsgID(newMsgID)
-	if err = c.setDataToStorage(ctx, newData); err != nil {
-		return fmt.Errorf("save data to storage: %w", err)
+	if err = c.send(ctx, newData, replacedMessage{}); err != nil {
+		return err
 	}
-	return nil
+	return c.sendOutbound(ctx, newData)
 }
 
-func (c *callbackManager) ProcessMsg(ctx context.Context, msgID, chatID int64, message string) error {
+func (c *callbackManager) ProcessMsg(ctx context.Context, actor Actor, msgID, chatID int64, message string) (err error) {
+	ctx = WithActor(ctx, actor)
+	start := time.Now()
+	var msg, processorName string
+	ctx, span := c.startSpan(ctx, "tgmanager.ProcessMsg",
+		Int64Attribute(AttrChatID, chatID), Int64Attribute(AttrMessageID, msgID))
+	defer func() {
+		span.SetAttributes(StringAttribute(AttrProcessor, processorName))
+		endSpan(span, err)
+	}()
+	defer func() {
+		c.logResult(ctx, "process message", start, err,
+			slog.Int64(logKeyChatID, chatID),
+			slog.Int64(logKeyMessageID, msgID),
+			slog.String(logKeyProcessor, processorName),
+		)
+		c.incCounter(MetricMessagesTotal, Labels{metricLabelProcessor: processorName})
+		c.countError("process_message", err)
+	}()
+
+	defer func() {
+		err = wrapError("process message", msg, chatID, msgID, err)
+	}()
+
 	message = strings.ReplaceAll(message, fmt.Sprintf("@%s", c.botName), "")
 
 	msg, key, userPayload, err := parseSwitchInlineInput(message)
@@ -222,80 +399,259 @@ func (c *callbackManager) ProcessMsg(ctx context.Context, msgID, chatID int64, m
 		return ErrMessageProcessorNotFound
 	}
 
-	outData, processorName, err := processor(ctx, key, userPayload, chatID, msgID)
+	// only messages the bot handles take tokens, chatter is left to other handlers
+	if !c.allowAction(ctx, actor, chatID) {
+		return c.throttled(ctx, chatID, nil)
+	}
+
+	var outData InOutData
+	err = c.runProcessor(ctx, msg, c.inlineTimeout(msg), func(ctx context.Context) (err error) {
+		outData, processorName, err = processor(ctx, key, userPayload, chatID, msgID)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("processing msg: %w", err)
+		return err
 	}
 	if outData == nil {
 		return nil
 	}
-
-	if err = c.SendNode(ctx, outData, processorName); err != nil {
-		return fmt.Errorf("sending node: %w", err)
-	}
-	return nil
+	return c.SendNode(ctx, outData, processorName)
 }
 
-func (c *callbackManager) ProcessCallback(ctx context.Context, oldMsgID, chatID int64, callbackValue string) error {
+func (c *callbackManager) ProcessCallback(ctx context.Context, actor Actor, queryID string, oldMsgID, chatID int64, callbackValue string) (err error) {
+	ctx = WithActor(ctx, actor)
+	start := time.Now()
 	var callback callbackParser
-	if err := callback.parseCallback(callbackValue); err != nil {
-		return errors.New("invalid callback")
+	ctx, span := c.startSpan(ctx, "tgmanager.ProcessCallback",
+		Int64Attribute(AttrChatID, chatID), Int64Attribute(AttrMessageID, oldMsgID))
+	defer func() {
+		span.SetAttributes(
+			StringAttribute(AttrProcessor, callback.Processor),
+			StringAttribute(AttrCallbackType, callback.ProcessorType.String()),
+		)
+		endSpan(span, err)
+	}()
+	defer func() {
+		c.logResult(ctx, "process callback", start, err,
+			slog.Int64(logKeyChatID, chatID),
+			slog.Int64(logKeyMessageID, oldMsgID),
+			slog.String(logKeyProcessor, callback.Processor),
+			slog.String(logKeyCallbackType, callback.ProcessorType.String()),
+		)
+		c.incCounter(MetricCallbacksTotal, Labels{
+			metricLabelProcessor:    c.metricProcessor(callback.Processor),
+			metricLabelCallbackType: callback.ProcessorType.String(),
+		})
+		c.countError("process_callback", err)
+	}()
+
+	defer func() {
+		err = wrapError("process callback", callback.Processor, chatID, oldMsgID, err)
+	}()
+
+	answer := c.callbackAnswerer(ctx, queryID)
+	defer answer(CallbackAnswer{})
+
+	// throttled presses are rejected transitions, ignore buttons are no transitions at all
+	event := TransitionEvent{
+		UserID: actor.UserID,
+		ChatID: chatID,
+		Time:   start,
+	}
+	defer func() {
+		if callback.ProcessorType != CallbackProcessorTypeIgnore {
+			c.emitTransition(ctx, event, err)
+		}
+	}()
+
+	if !c.allowAction(ctx, actor, chatID) {
+		return c.throttled(ctx, chatID, answer)
+	}
+
+	if err = callback.parseCallback(callbackValue); err != nil {
+		return err
 	}
 
 	if callback.ProcessorType == CallbackProcessorTypeIgnore {
 		return nil
 	}
+	event.CallbackType = callback.ProcessorType.String()
 
-	data, err := c.dataProcessor(ctx, oldMsgID, callback)
-	if err != nil {
+	state, err := c.getDataFromStorage(ctx, oldMsgID)
+	if err != nil && !errors.Is(err, ErrStateNotFound) {
 		return err
 	}
+	old := state.replaced()
+	var ownerID int64
+	if state != nil {
+		if c.isForeign(state, actor) {
+			answer(c.foreignUserAnswer)
+			return ErrNotMenuOwner
+		}
+		ownerID = state.OwnerID
+		event.From = state.Processor
+		state.setActor(actor)
+	}
+
+	// a failed processor may still give an error screen to show
+	data, procErr := c.dataProcessor(ctx, state, callback, &event)
+	if data == nil && procErr != nil {
+		return procErr
+	}
 	if data == nil {
-		c.clearFlow(ctx, oldMsgID, chatID)
+		event.Outcome = TransitionOutcomeClosed
+		c.clearFlow(ctx, oldMsgID, chatID, old.linkedIDs(), state != nil)
 		return nil
 	}
+	data.setActor(actor)
+	data.setOwnerID(ownerID)
 
-	tgContainer, err := data.generateTelegramContainer()
+	// the toast confirms the result, so it is shown only once the node is delivered,
+	// a failed send gets the empty answer
+	if err = c.send(ctx, data, old); err != nil {
+		return err
+	}
+	answer(data.getCallbackAnswer())
+	span.SetAttributes(StringAttribute(AttrAppearType, data.getAppearType().String()))
+
+	if data.getAppearType() == CallBackAppearTypeResendDeleteOld {
+		c.deleteDataFromStorage(ctx, oldMsgID, state != nil)
+	}
+	if procErr != nil {
+		return procErr
+	}
+	return c.sendOutbound(ctx, data)
+}
+
+// send delivers data to telegram and saves its state. An album goes first, messages longer than
+// the telegram limits are sent as several parts, the keyboard is always attached to the last one.
+// The album and the leading parts of the old message are removed unless the old message is kept.
+func (c *callbackManager) send(ctx context.Context, data InOutData, old replacedMessage) error {
+	allowed, err := c.processorFilter(ctx, data.GetActor().UserID, data.GetChatID())
 	if err != nil {
 		return err
 	}
 
-	newMsgID, err := c.sender.SendMsg(ctx, tgContainer)
+	tgCont, err := data.generateTelegramContainer(allowed)
+	if err != nil {
+		return fmt.Errorf("%w: generate container: %w", ErrInvalidData, err)
+	}
+
+	containers, err := splitTelegramContainer(tgCont)
+	if err != nil {
+		return fmt.Errorf("%w: split message: %w", ErrInvalidData, err)
+	}
+
+	outboxID, err := c.outboxPending(ctx, data)
 	if err != nil {
 		return err
 	}
-	data.setMsgID(newMsgID)
 
-	if data.getAppearType() == CallBackAppearTypeResendDeleteOld {
-		c.deleteDataFromStorage(ctx, oldMsgID)
+	album := data.GetAlbum()
+	last := &containers[len(containers)-1]
+	// telegram can't edit a message into several ones or turn text into media and back,
+	// so the old message is replaced instead
+	if last.AppearType == CallBackAppearTypeUpdate &&
+		(len(containers) > 1 || len(album) != 0 || (last.Media != nil) != old.hasMedia) {
+		last.AppearType = CallBackAppearTypeResendDeleteOld
+		data.setAppearType(CallBackAppearTypeResendDeleteOld)
 	}
 
+	var albumIDs []int64
+	if len(album) != 0 {
+		if albumIDs, err = c.sender.SendMediaGroup(ctx, tgCont.ChatID, tgCont.ThreadID, album); err != nil {
+			c.outboxDone(ctx, outboxID)
+			return fmt.Errorf("%w: sending tg media group: %w", ErrSendFailed, err)
+		}
+	}
+
+	partIDs := make([]int64, 0, len(containers)-1)
+	for _, part := range containers[:len(containers)-1] {
+		partID, err := c.sender.SendMsg(ctx, part)
+		if err != nil {
+			c.outboxDone(ctx, outboxID)
+			c.deleteMessages(tgCont.ChatID, append(albumIDs, partIDs...)...)
+			return fmt.Errorf("%w: sending tg msg part: %w", ErrSendFailed, err)
+		}
+		partIDs = append(partIDs, partID)
+	}
+
+	newMsgID, err := c.sender.SendMsg(ctx, *last)
+	if err != nil {
+		c.outboxDone(ctx, outboxID)
+		c.deleteMessages(tgCont.ChatID, append(albumIDs, partIDs...)...)
+		return fmt.Errorf("%w: sending tg msg: %w", ErrSendFailed, err)
+	}
+
+	prevMsgID := data.getMsgID()
+	data.setMsgID(newMsgID)
+	data.setAlbumMsgIDs(albumIDs)
+	data.setPartMsgIDs(partIDs)
+	c.outboxSent(ctx, outboxID, data)
 	if err = c.setDataToStorage(ctx, data); err != nil {
+		c.compensate(tgCont.ChatID, append(append(albumIDs, partIDs...), newMsgID))
 		return err
 	}
+	c.outboxDone(ctx, outboxID)
+	c.recordFlow(data)
+	if newMsgID != prevMsgID {
+		c.addGauge(MetricActiveStates, 1)
+	}
+
+	if last.AppearType != CallBackAppearTypeResend {
+		c.deleteMessages(tgCont.ChatID, old.linkedIDs()...)
+	}
 	return nil
 }
 
-func (c *callbackManager) deleteDataFromStorage(ctx context.Context, msgID int64) {
-	go func() {
-		if err := c.storage.DeleteState(ctx, msgID); err != nil {
-			if c.logger != nil {
-				c.logger.LogError(err)
-			}
+func (c *callbackManager) deleteMessages(chatID int64, msgIDs ...int64) {
+	if len(msgIDs) == 0 {
+		return
+	}
+	c.background.submit(context.Background(), "delete messages", func(context.Context) error {
+		for _, msgID := range msgIDs {
+			c.sender.DeleteMessage(msgID, chatID)
 		}
-	}()
+		return nil
+	})
 }
 
-func (c *callbackManager) getDataFromStorage(ctx context.Context, msgID int64) (InOutData, error) {
-	payload, err := c.storage.GetState(ctx, msgID)
+// deleteDataFromStorage removes the state in the background, stored tells whether it was found,
+// a missing state is deleted too but isn't counted in the active states.
+func (c *callbackManager) deleteDataFromStorage(ctx context.Context, msgID int64, stored bool) {
+	c.background.submit(ctx, fmt.Sprintf("delete state %d", msgID), func(ctx context.Context) error {
+		c.logAttrs(ctx, slog.LevelDebug, "delete state", slog.Int64(logKeyMessageID, msgID))
+		ctx, done := c.instrument(ctx, MetricStorageSeconds, "storage", "delete", Int64Attribute(AttrMessageID, msgID))
+		err := c.storage.DeleteState(ctx, msgID)
+		done(err)
+		if err == nil && stored {
+			c.addGauge(MetricActiveStates, -1)
+		}
+		return err
+	})
+}
+
+func (c *callbackManager) getDataFromStorage(ctx context.Context, msgID int64) (*inOutData, error) {
+	start := time.Now()
+	storageCtx, done := c.instrument(ctx, MetricStorageSeconds, "storage", "get", Int64Attribute(AttrMessageID, msgID))
+	payload, err := c.storage.GetState(storageCtx, msgID)
+	done(err)
+	c.logAttrs(ctx, slog.LevelDebug, "get state",
+		slog.Int64(logKeyMessageID, msgID),
+		slog.Bool("found", payload != nil),
+		slog.Duration(logKeyDuration, time.Since(start)),
+	)
 	if err != nil {
-		return nil, fmt.Errorf("getting data from storage: %w", err)
+		return nil, fmt.Errorf("%w: getting state: %w", ErrStorage, err)
+	}
+	if payload == nil {
+		return nil, ErrStateNotFound
 	}
 
 	var data inOutData
 
 	if err = json.Unmarshal(payload, &data); err != nil {
-		return nil, fmt.Errorf("json unmarshal")
+		return nil, fmt.Errorf("%w: json unmarshal: %w", ErrStateCorrupt, err)
 	}
 	return &data, nil
 }
@@ -303,83 +659,130 @@ func (c *callbackManager) getDataFromStorage(ctx context.Context, msgID int64) (
 func (c *callbackManager) setDataToStorage(ctx context.Context, data InOutData) error {
 	dataStruct, ok := data.(*inOutData)
 	if !ok {
-		return errors.New("invalid inbound data")
+		return fmt.Errorf("%w: unsupported implementation %T", ErrInvalidData, data)
 	}
 
 	dataPayload, err := json.Marshal(dataStruct)
 	if err != nil {
-		return errors.New("json marshal")
-	}
-
-	if err = c.storage.SaveState(ctx, data.getMsgID(), dataPayload); err != nil {
-		return fmt.Errorf("saving data to storage: %w", err)
+		return fmt.Errorf("%w: json marshal: %w", ErrInvalidData, err)
+	}
+
+	start := time.Now()
+	storageCtx, done := c.instrument(ctx, MetricStorageSeconds, "storage", "save",
+		Int64Attribute(AttrChatID, data.GetChatID()), Int64Attribute(AttrMessageID, data.getMsgID()))
+	err = c.storage.SaveState(storageCtx, data.getMsgID(), dataPayload)
+	done(err)
+	c.logAttrs(ctx, slog.LevelDebug, "save state",
+		slog.Int64(logKeyChatID, data.GetChatID()),
+		slog.Int64(logKeyMessageID, data.getMsgID()),
+		slog.Duration(logKeyDuration, time.Since(start)),
+	)
+	if err != nil {
+		return fmt.Errorf("%w: saving state: %w", ErrStorage, err)
 	}
 	return nil
 }
 
-func (c *callbackManager) dataProcessor(ctx context.Context, msgID int64, callback callbackParser) (InOutData, error) {
-	payload, err := c.storage.GetState(ctx, msgID)
-	if err != nil {
-		return nil, err
-	}
-	if payload == nil {
+// dataProcessor runs the processor of the pressed button, event gets the target and the button label.
+func (c *callbackManager) dataProcessor(ctx context.Context, data *inOutData, callback callbackParser, event *TransitionEvent) (InOutData, error) {
+	if data == nil {
 		return nil, nil
 	}
 
-	var data = &inOutData{}
-
-	if err = json.Unmarshal(payload, data); err != nil {
-		return nil, fmt.Errorf("json unmarshal: %w", err)
-	}
-
-	var nxtNode nextNode
+	var (
+		nxtNode nextNode
+		err     error
+	)
 	if callback.ProcessorType == CallbackProcessorTypeProcess {
 		if nxtNode, err = data.getProcessorNodeByIndex(callback.Idx); err != nil {
-			return nil, errors.New("invalid index")
+			return nil, err
 		}
 	} else {
 		var valid bool
 		nxtNode, valid = data.getMenuNodeByType(callback.ProcessorType)
 		if !valid {
-			return nil, errors.New("invalid non processor type callback")
+			return nil, fmt.Errorf("%w: no %s menu node", ErrStaleIndex, callback.ProcessorType)
 		}
 	}
 
 	defNextNode := nxtNode.getDefault()
 	if defNextNode == nil {
-		return nil, errors.New("invalid next node")
+		return nil, ErrInvalidNode
 	}
+	event.To = defNextNode.getProcessorName()
+	event.ButtonLabel = nxtNode.getButtonLabel()
 
 	if defNextNode.getProcessorName() == "" {
 		return nil, nil
 	}
 
+	// roles are resolved for the presser only, never for the actor saved in the state
+	actor, _ := ActorFromContext(ctx)
+	allowed, err := c.processorFilter(ctx, actor.UserID, data.ChatID)
+	if err != nil {
+		return nil, err
+	}
+	if allowed != nil && !allowed(defNextNode.getProcessorName()) {
+		return nil, ErrProcessorForbidden
+	}
+
 	processor, ok := c.allProcessors[defNextNode.getProcessorName()]
 	if !ok {
-		return nil, errors.New("processor not found")
+		return nil, &ManagerError{Op: "process callback", Processor: defNextNode.getProcessorName(),
+			ChatID: data.ChatID, MsgID: data.getMsgID(), Err: ErrProcessorNotFound}
 	}
 	if processor == nil {
 		return nil, nil
 	}
 
+	var back *nextNode
+	if node, ok := data.getMenuNodeByType(CallbackProcessorTypeBack); ok {
+		back = &node
+	}
+
 	data.ExternalPayload = defNextNode.getExternalPayload()
 	data.MenuNodes = nil
 	data.ProcessorNodes = nil
-
-	newData, err := processor(ctx, data)
+	data.Media = nil
+	data.Album = nil
+	data.Access = ""
+	data.ParseMode = ""
+
+	var newData InOutData
+	err = c.runProcessor(ctx, defNextNode.getProcessorName(), c.callbackTimeout(defNextNode.getProcessorName()), func(ctx context.Context) (err error) {
+		newData, err = processor(ctx, data)
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return c.errorScreen(ctx, data, back, defNextNode, err)
 	}
 	if newData != nil {
 		newData.setAppearType(c.defaultAppearType)
+		newData.setProcessor(defNextNode.getProcessorName())
 	}
 
 	return newData, nil
 }
 
-func (c *callbackManager) clearFlow(ctx context.Context, msgID, chatID int64) {
-	go func() {
-		c.sender.DeleteMessage(msgID, chatID)
-		c.deleteDataFromStorage(ctx, msgID)
-	}()
+func (c *callbackManager) clearFlow(ctx context.Context, msgID, chatID int64, linkedIDs []int64, stored bool) {
+	c.deleteMessages(chatID, append([]int64{msgID}, linkedIDs...)...)
+	c.deleteDataFromStorage(ctx, msgID, stored)
+}
+
+// isForeign reports whether the actor is not allowed to press buttons of the menu opened by someone else.
+func (c *callbackManager) isForeign(state *inOutData, actor Actor) bool {
+	if actor.ChatType == ChatTypePrivate {
+		return false
+	}
+	switch state.Access {
+	case NodeAccessShared:
+		return false
+	case NodeAccessOwner:
+	default:
+		if !c.ownerOnly {
+			return false
+		}
+	}
+	// an unknown owner or presser can't be told apart from a foreign user
+	return state.OwnerID == 0 || actor.UserID == 0 || actor.UserID != state.OwnerID
 }
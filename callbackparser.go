@@ -1,7 +1,6 @@
 package tgmanager
 
 import (
-	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -32,24 +31,24 @@ func (c *callbackParser) parseCallback(in string) error {
 	}
 	items := strings.Split(in, callbackDivider)
 	if len(items) != 3 {
-		return errors.New("invalid callback")
+		return fmt.Errorf("%w: expected 3 parts, got %d", ErrInvalidCallback, len(items))
 	}
 
 	c.Processor = items[0]
 
 	processorTypeNumber, err := strconv.Atoi(items[1])
 	if err != nil {
-		return errors.New("invalid callback")
+		return fmt.Errorf("%w: processor type: %w", ErrInvalidCallback, err)
 	}
 
 	c.ProcessorType = CallbackProcessorType(processorTypeNumber)
 	if !c.ProcessorType.IsValid() {
-		return errors.New("invalid callback")
+		return fmt.Errorf("%w: unknown processor type %d", ErrInvalidCallback, processorTypeNumber)
 	}
 
 	idxNumber, err := strconv.Atoi(items[2])
 	if err != nil || idxNumber < 0 {
-		return errors.New("invalid callback")
+		return fmt.Errorf("%w: invalid index %q", ErrInvalidCallback, items[2])
 	}
 	c.Idx = int64(idxNumber)
 	return nil
@@ -0,0 +1,137 @@
+package tgmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+const (
+	outboxPhasePending = "pending"
+	outboxPhaseSent    = "sent"
+)
+
+// OutboxStore keeps intended sends until their state is saved, see ConsistencyStrategyOutbox.
+type OutboxStore interface {
+	SaveOutbox(ctx context.Context, id string, data []byte) (err error)
+	DeleteOutbox(ctx context.Context, id string) (err error)
+	ListOutbox(ctx context.Context) (items map[string][]byte, err error)
+}
+
+type outboxEntry struct {
+	Phase string
+	Data  inOutData
+}
+
+// SetConsistency selects how a failed state save after a successful send is handled.
+// With ConsistencyStrategyCompensate the sent messages are deleted, so the user never sees
+// buttons without state. An edited message is deleted as well since it can't be restored.
+// With ConsistencyStrategyOutbox sends are persisted beforehand and ReconcileOutbox saves
+// the state of the sent ones later, e.g. on restart.
+func (c *callbackManager) SetConsistency(strategy ConsistencyStrategy, outbox OutboxStore) error {
+	if strategy != "" && !strategy.IsValid() {
+		return ErrInvalidConsistencyStrategy
+	}
+	if strategy == ConsistencyStrategyOutbox && outbox == nil {
+		return errors.New("outbox store is required")
+	}
+	c.consistency = strategy
+	c.outbox = outbox
+	return nil
+}
+
+// ReconcileOutbox saves the state of messages sent before a failure. Entries which never
+// reached telegram are dropped, whether they were delivered is unknown.
+func (c *callbackManager) ReconcileOutbox(ctx context.Context) error {
+	if c.outbox == nil {
+		return nil
+	}
+
+	items, err := c.outbox.ListOutbox(ctx)
+	if err != nil {
+		return fmt.Errorf("listing outbox: %w", err)
+	}
+
+	var errs []error
+	for id, payload := range items {
+		var entry outboxEntry
+		if err = json.Unmarshal(payload, &entry); err != nil {
+			errs = append(errs, fmt.Errorf("outbox %s: %w: json unmarshal: %w", id, ErrStateCorrupt, err))
+			continue
+		}
+		if entry.Phase == outboxPhaseSent {
+			if err = c.setDataToStorage(ctx, &entry.Data); err != nil {
+				errs = append(errs, fmt.Errorf("outbox %s: %w", id, err))
+				continue
+			}
+		}
+		if err = c.outbox.DeleteOutbox(ctx, id); err != nil {
+			errs = append(errs, fmt.Errorf("outbox %s: deleting: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// outboxPending records the intended send, it returns an empty id without outbox.
+func (c *callbackManager) outboxPending(ctx context.Context, data InOutData) (string, error) {
+	if c.consistency != ConsistencyStrategyOutbox {
+		return "", nil
+	}
+
+	id, err := randomID(16)
+	if err != nil {
+		return "", fmt.Errorf("generating outbox id: %w", err)
+	}
+
+	if err = c.saveOutbox(ctx, id, outboxPhasePending, data); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// outboxSent records the sent message ids, a failure is only logged since the send is done.
+func (c *callbackManager) outboxSent(ctx context.Context, id string, data InOutData) {
+	if id == "" {
+		return
+	}
+	if err := c.saveOutbox(ctx, id, outboxPhaseSent, data); err != nil {
+		c.logError(ctx, "save outbox", err, slog.String("outbox_id", id))
+	}
+}
+
+func (c *callbackManager) outboxDone(ctx context.Context, id string) {
+	if id == "" {
+		return
+	}
+	if err := c.outbox.DeleteOutbox(ctx, id); err != nil {
+		c.logError(ctx, "delete outbox", fmt.Errorf("deleting outbox: %w", err), slog.String("outbox_id", id))
+	}
+}
+
+func (c *callbackManager) saveOutbox(ctx context.Context, id, phase string, data InOutData) error {
+	dataStruct, ok := data.(*inOutData)
+	if !ok {
+		return fmt.Errorf("%w: unsupported implementation %T", ErrInvalidData, data)
+	}
+
+	payload, err := json.Marshal(outboxEntry{Phase: phase, Data: *dataStruct})
+	if err != nil {
+		return fmt.Errorf("%w: json marshal: %w", ErrInvalidData, err)
+	}
+	if err = c.outbox.SaveOutbox(ctx, id, payload); err != nil {
+		return fmt.Errorf("saving outbox: %w", err)
+	}
+	return nil
+}
+
+// compensate removes messages whose state couldn't be saved.
+func (c *callbackManager) compensate(chatID int64, msgIDs []int64) {
+	if c.consistency != ConsistencyStrategyCompensate {
+		return
+	}
+	for _, msgID := range msgIDs {
+		c.sender.DeleteMessage(msgID, chatID)
+	}
+}
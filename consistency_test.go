@@ -0,0 +1,134 @@
+package tgmanager
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type testStorage struct {
+	mu       sync.Mutex
+	states   map[int64][]byte
+	failSave bool
+}
+
+func (s *testStorage) SaveState(_ context.Context, key int64, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failSave {
+		return errors.New("storage is down")
+	}
+	if s.states == nil {
+		s.states = make(map[int64][]byte)
+	}
+	s.states[key] = data
+	return nil
+}
+
+func (s *testStorage) GetState(_ context.Context, key int64) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.states[key], nil
+}
+
+func (s *testStorage) DeleteState(_ context.Context, key int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, key)
+	return nil
+}
+
+type testOutbox struct {
+	items map[string][]byte
+}
+
+func (o *testOutbox) SaveOutbox(_ context.Context, id string, data []byte) error {
+	o.items[id] = data
+	return nil
+}
+
+func (o *testOutbox) DeleteOutbox(_ context.Context, id string) error {
+	delete(o.items, id)
+	return nil
+}
+
+func (o *testOutbox) ListOutbox(context.Context) (map[string][]byte, error) {
+	return o.items, nil
+}
+
+type testDeleteSender struct {
+	testQueueSender
+	deleted []int64
+}
+
+func (s *testDeleteSender) DeleteMessage(messageID int64, _ int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleted = append(s.deleted, messageID)
+}
+
+func newTestConsistencyManager(t *testing.T, storage *testStorage, sender *testDeleteSender) CallbackManager {
+	manager, err := NewCallbackManager("default", CallBackAppearTypeUpdate, nil, storage, sender, nil, nil, nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	t.Cleanup(func() { _ = manager.Close(context.Background()) })
+	err = manager.AddProcessors(Processor{
+		Name: "start",
+		Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+			data.AddNode(NewDefaultNode("next", "start", CallbackProcessorTypeProcess, nil))
+			return data, nil
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	return manager
+}
+
+func Test_consistencyCompensate(t *testing.T) {
+	storage := &testStorage{failSave: true}
+	sender := &testDeleteSender{}
+	manager := newTestConsistencyManager(t, storage, sender)
+	if err := manager.SetConsistency(ConsistencyStrategyCompensate, nil); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err := manager.SendNode(context.Background(), NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "start")
+	if !errors.Is(err, ErrStorage) {
+		t.Fatal("expected storage error, got", err)
+	}
+	if len(sender.deleted) != 1 || sender.deleted[0] != 1 {
+		t.Error("sent message must be deleted", "deleted:", sender.deleted)
+	}
+}
+
+func Test_consistencyOutbox(t *testing.T) {
+	storage := &testStorage{failSave: true}
+	sender := &testDeleteSender{}
+	outbox := &testOutbox{items: make(map[string][]byte)}
+	manager := newTestConsistencyManager(t, storage, sender)
+	if err := manager.SetConsistency(ConsistencyStrategyOutbox, outbox); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err := manager.SendNode(context.Background(), NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "start")
+	if !errors.Is(err, ErrStorage) {
+		t.Fatal("expected storage error, got", err)
+	}
+	if len(outbox.items) != 1 || len(sender.deleted) != 0 {
+		t.Error("send must stay in the outbox", "outbox:", len(outbox.items), "deleted:", sender.deleted)
+	}
+
+	storage.failSave = false
+	if err = manager.ReconcileOutbox(context.Background()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(outbox.items) != 0 {
+		t.Error("outbox must be empty after reconcile")
+	}
+	if state, _ := storage.GetState(context.Background(), 1); state == nil {
+		t.Error("state of the sent message must be saved")
+	}
+}
@@ -0,0 +1,76 @@
+package tgmanager
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ManagerError carries the details of a failed manager operation, the cause is one of
+// the package sentinels optionally wrapping the underlying error, so errors.Is works on both.
+type ManagerError struct {
+	Op        string
+	Processor string
+	ChatID    int64
+	MsgID     int64
+	// CorrelationID is shown to the user by the error screen
+	CorrelationID string
+	Err           error
+}
+
+func (e *ManagerError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Op)
+	if e.Processor != "" {
+		fmt.Fprintf(&b, ": processor %s", e.Processor)
+	}
+	if e.ChatID != 0 {
+		fmt.Fprintf(&b, ": chat %d", e.ChatID)
+	}
+	if e.MsgID != 0 {
+		fmt.Fprintf(&b, ": message %d", e.MsgID)
+	}
+	if e.CorrelationID != "" {
+		fmt.Fprintf(&b, ": correlation id %s", e.CorrelationID)
+	}
+	fmt.Fprintf(&b, ": %s", e.Err)
+	return b.String()
+}
+
+func (e *ManagerError) Unwrap() error {
+	return e.Err
+}
+
+// userErrors are caused by what the user did, e.g. pressed a button of an outdated menu,
+// rather than by a bug in processors or an infrastructure failure.
+var userErrors = []error{
+	ErrInvalidCallback,
+	ErrStaleIndex,
+	ErrStateNotFound,
+	ErrMessageProcessorNotFound,
+	ErrNotMenuOwner,
+	ErrProcessorForbidden,
+	ErrRateLimited,
+}
+
+// IsUserError reports whether err is caused by the user input rather than a bug or an outage.
+func IsUserError(err error) bool {
+	for _, userErr := range userErrors {
+		if errors.Is(err, userErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// wrapError adds the processor name, chat and message to err unless it already has them.
+func wrapError(op, processor string, chatID, msgID int64, err error) error {
+	if err == nil {
+		return nil
+	}
+	var managerErr *ManagerError
+	if errors.As(err, &managerErr) {
+		return err
+	}
+	return &ManagerError{Op: op, Processor: processor, ChatID: chatID, MsgID: msgID, Err: err}
+}
@@ -0,0 +1,89 @@
+package tgmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_managerErrors(t *testing.T) {
+	storage := &testStorage{}
+	manager := newTestConsistencyManager(t, storage, &testDeleteSender{})
+	ctx := context.Background()
+	if err := manager.SendNode(ctx, NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "start"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := storage.SaveState(ctx, 7, []byte("{")); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for _, tCase := range []struct {
+		name     string
+		msgID    int64
+		callback string
+		err      error
+		user     bool
+	}{
+		{name: "invalid", msgID: 1, callback: "start>x>0", err: ErrInvalidCallback, user: true},
+		{name: "stale index", msgID: 1, callback: "start>0>5", err: ErrStaleIndex, user: true},
+		{name: "corrupt state", msgID: 7, callback: "start>0>0", err: ErrStateCorrupt, user: false},
+	} {
+		t.Run(tCase.name, func(t *testing.T) {
+			err := manager.ProcessCallback(ctx, Actor{}, "", tCase.msgID, 1, tCase.callback)
+			if !errors.Is(err, tCase.err) {
+				t.Fatal("expected", tCase.err, "got", err)
+			}
+			var managerErr *ManagerError
+			if !errors.As(err, &managerErr) || managerErr.ChatID != 1 || managerErr.MsgID != tCase.msgID {
+				t.Error("error must carry chat and message", err)
+			}
+			if IsUserError(err) != tCase.user {
+				t.Error("unexpected user error classification", err)
+			}
+		})
+	}
+
+	err := manager.SendNode(ctx, NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "unknown")
+	var managerErr *ManagerError
+	if !errors.Is(err, ErrProcessorNotFound) || !errors.As(err, &managerErr) || managerErr.Processor != "unknown" {
+		t.Error("expected processor not found with processor name, got", err)
+	}
+}
+
+func Test_managerErrors_processMsg(t *testing.T) {
+	manager := newTestConsistencyManager(t, &testStorage{}, &testDeleteSender{})
+	if err := manager.AddInlineProcessors(InlineProcessor{
+		Name: "search",
+		Processor: func(context.Context, string, string, int64, int64) (InOutData, string, error) {
+			return nil, "", nil
+		},
+	}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	manager.SetRateLimit(RateLimitConfig{ChatRate: Rate{Burst: 1, Interval: time.Hour}})
+
+	ctx := context.Background()
+	for _, tCase := range []struct {
+		name      string
+		message   string
+		err       error
+		processor string
+	}{
+		{name: "chatter", message: "hello", err: ErrMessageProcessorNotFound},
+		{name: "allowed", message: "search" + inlineDivider + "query"},
+		{name: "rate limited", message: "search" + inlineDivider + "query", err: ErrRateLimited, processor: "search"},
+	} {
+		err := manager.ProcessMsg(ctx, Actor{UserID: 1}, 3, 1, tCase.message)
+		if !errors.Is(err, tCase.err) {
+			t.Fatal(tCase.name, "expected", tCase.err, "got", err)
+		}
+		if err == nil {
+			continue
+		}
+		var managerErr *ManagerError
+		if !errors.As(err, &managerErr) || managerErr.ChatID != 1 || managerErr.MsgID != 3 || managerErr.Processor != tCase.processor {
+			t.Error(tCase.name, "error must carry chat, message and processor", err)
+		}
+	}
+}
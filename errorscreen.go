@@ -0,0 +1,159 @@
+package tgmanager
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+const (
+	defaultRetryLabel = "Retry"
+	defaultBackLabel  = "Back"
+	defaultTimeoutMsg = "The request took too long, please try again."
+	defaultErrorIDFmt = "%s\n\nError ID: %s"
+	correlationIDSize = 4
+)
+
+// ErrorScreen maps processor errors to what the user sees instead of the failed node.
+type ErrorScreen struct {
+	// Err is matched with errors.Is
+	Err error
+	// Match is used for error types, e.g. with errors.As, when Err is nil
+	Match func(err error) bool
+	// Message is shown with Retry and Back buttons, the default message is used when empty
+	Message string
+	// Processor renders the screen instead of Message, it gets the prepared data and
+	// may read the failure with ErrorInfoFromContext
+	Processor string
+}
+
+func (s ErrorScreen) matches(err error) bool {
+	if s.Err != nil {
+		return errors.Is(err, s.Err)
+	}
+	return s.Match != nil && s.Match(err)
+}
+
+// ErrorScreenConfig enables error screens, the first matching screen is used.
+// Errors not matched by any screen get the default message with Retry and Back buttons.
+type ErrorScreenConfig struct {
+	Screens    []ErrorScreen
+	RetryLabel string
+	BackLabel  string
+	// TimeoutMessage is shown for ErrProcessorTimeout unless a screen matches it
+	TimeoutMessage string
+	// ErrorIDFormat renders the screen text from the message and the correlation id,
+	// "%s\n\nError ID: %s" when empty
+	ErrorIDFormat string
+}
+
+// ErrorInfo describes the failure rendered by an error screen processor.
+type ErrorInfo struct {
+	Err           error
+	CorrelationID string
+}
+
+type errorInfoKey struct{}
+
+// ErrorInfoFromContext returns the failure inside an error screen processor.
+func ErrorInfoFromContext(ctx context.Context) (ErrorInfo, bool) {
+	info, ok := ctx.Value(errorInfoKey{}).(ErrorInfo)
+	return info, ok
+}
+
+// SetErrorScreens shows an error screen when a processor fails on a button press.
+// The screen shows a correlation id which is also logged and returned in ManagerError.
+func (c *callbackManager) SetErrorScreens(config ErrorScreenConfig) {
+	if config.RetryLabel == "" {
+		config.RetryLabel = defaultRetryLabel
+	}
+	if config.BackLabel == "" {
+		config.BackLabel = defaultBackLabel
+	}
+	if config.TimeoutMessage == "" {
+		config.TimeoutMessage = defaultTimeoutMsg
+	}
+	if config.ErrorIDFormat == "" {
+		config.ErrorIDFormat = defaultErrorIDFmt
+	}
+	c.errorScreens = &config
+}
+
+// errorScreen wraps the processor error and prepares the screen replacing the failed node.
+// Retry repeats the failed callback, Back is the back button of the menu the user pressed in.
+func (c *callbackManager) errorScreen(ctx context.Context, state *inOutData, back *nextNode, failed *defaultNode, err error) (InOutData, error) {
+	managerErr := &ManagerError{
+		Op:        "process callback",
+		Processor: failed.getProcessorName(),
+		ChatID:    state.ChatID,
+		MsgID:     state.getMsgID(),
+		Err:       err,
+	}
+	if c.errorScreens == nil {
+		return nil, managerErr
+	}
+
+	correlationID, idErr := randomID(correlationIDSize)
+	if idErr != nil {
+		c.logError(ctx, "generate correlation id", idErr)
+		return nil, managerErr
+	}
+	managerErr.CorrelationID = correlationID
+
+	var screen ErrorScreen
+	for _, item := range c.errorScreens.Screens {
+		if item.matches(err) {
+			screen = item
+			break
+		}
+	}
+
+	message := screen.Message
+	if message == "" && errors.Is(err, ErrProcessorTimeout) {
+		message = c.errorScreens.TimeoutMessage
+	}
+	if message == "" {
+		message = c.defaultMsg
+	}
+	data := NewInOutData(state.ChatID, state.getMsgID(), fmt.Sprintf(c.errorScreens.ErrorIDFormat, message, correlationID), c.defaultAppearType)
+	data.AddNode(NewDefaultNode(c.errorScreens.RetryLabel, failed.getProcessorName(), CallbackProcessorTypeProcess, failed.getExternalPayload()))
+	if back != nil {
+		backNode := *back
+		backNode.ButtonLabel = c.errorScreens.BackLabel
+		data.AddNode(&backNode)
+	}
+
+	if screen.Processor == "" {
+		return data, managerErr
+	}
+	processor, ok := c.allProcessors[screen.Processor]
+	if !ok || processor == nil {
+		c.logError(ctx, "error screen", &ManagerError{Op: "error screen", Processor: screen.Processor, Err: ErrProcessorNotFound})
+		return data, managerErr
+	}
+	ctx = context.WithValue(ctx, errorInfoKey{}, ErrorInfo{Err: err, CorrelationID: correlationID})
+	var screenData InOutData
+	screenErr := c.runProcessor(ctx, screen.Processor, c.callbackTimeout(screen.Processor), func(ctx context.Context) (err error) {
+		screenData, err = processor(ctx, data)
+		return err
+	})
+	if screenErr != nil {
+		c.logError(ctx, "error screen", &ManagerError{Op: "error screen", Processor: screen.Processor, Err: screenErr})
+		return data, managerErr
+	}
+	if screenData == nil {
+		return data, managerErr
+	}
+	screenData.setAppearType(c.defaultAppearType)
+	return screenData, managerErr
+}
+
+func randomID(size int) (string, error) {
+	raw := make([]byte, size)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
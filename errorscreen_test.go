@@ -0,0 +1,126 @@
+package tgmanager
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_errorScreen(t *testing.T) {
+	errFailed := errors.New("database is down")
+	sender := &testDeleteSender{}
+	manager, err := NewCallbackManager("default", CallBackAppearTypeUpdate, nil, &testStorage{}, sender, nil, nil, nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	t.Cleanup(func() { _ = manager.Close(context.Background()) })
+	err = manager.AddProcessors(Processor{
+		Name: "start",
+		Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+			data.AddNode(NewDefaultNode("load", "load", CallbackProcessorTypeProcess, nil))
+			return data, nil
+		},
+	}, Processor{
+		Name: "load",
+		Processor: func(context.Context, InOutData) (InOutData, error) {
+			return nil, errFailed
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	manager.SetErrorScreens(ErrorScreenConfig{
+		Screens:       []ErrorScreen{{Err: errFailed, Message: "try later"}},
+		ErrorIDFormat: "%s\n\nКод ошибки: %s",
+	})
+
+	ctx := context.Background()
+	if err = manager.SendNode(ctx, NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "start"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for msgID := int64(1); msgID <= 2; msgID++ {
+		err = manager.ProcessCallback(ctx, Actor{}, "", msgID, 1, "load>0>0")
+		var managerErr *ManagerError
+		if !errors.Is(err, errFailed) || !errors.As(err, &managerErr) || managerErr.CorrelationID == "" {
+			t.Fatal("expected processor error with correlation id, got", err)
+		}
+		screen := sender.messages[len(sender.messages)-1]
+		if screen != "try later\n\nКод ошибки: "+managerErr.CorrelationID {
+			t.Error("unexpected error screen:", screen)
+		}
+	}
+}
+
+func Test_processorGuard(t *testing.T) {
+	sender := &testDeleteSender{}
+	manager, err := NewCallbackManager("default", CallBackAppearTypeUpdate, nil, &testStorage{}, sender, nil, nil, nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	t.Cleanup(func() { _ = manager.Close(context.Background()) })
+	err = manager.AddProcessors(Processor{
+		Name: "start",
+		Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+			data.AddNode(NewDefaultNode("panic", "panic", CallbackProcessorTypeProcess, nil))
+			data.AddNode(NewDefaultNode("hang", "hang", CallbackProcessorTypeProcess, nil))
+			return data, nil
+		},
+	}, Processor{
+		Name: "panic",
+		Processor: func(context.Context, InOutData) (InOutData, error) {
+			panic("boom")
+		},
+	}, Processor{
+		Name:    "hang",
+		Timeout: 10 * time.Millisecond,
+		Processor: func(ctx context.Context, _ InOutData) (InOutData, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	manager.SetErrorScreens(ErrorScreenConfig{})
+
+	ctx := context.Background()
+	if err = manager.SendNode(ctx, NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "start"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err = manager.ProcessCallback(ctx, Actor{}, "", 1, 1, "panic>0>0")
+	var panicErr *PanicError
+	if !errors.Is(err, ErrProcessorPanic) || !errors.As(err, &panicErr) || len(panicErr.Stack) == 0 {
+		t.Error("expected panic error with stack, got", err)
+	}
+
+	err = manager.ProcessCallback(ctx, Actor{}, "", 1, 1, "hang>0>1")
+	if !errors.Is(err, ErrProcessorTimeout) {
+		t.Fatal("expected timeout error, got", err)
+	}
+	if screen := sender.messages[len(sender.messages)-1]; !strings.HasPrefix(screen, defaultTimeoutMsg) {
+		t.Error("expected timeout screen, got", screen)
+	}
+
+	// the inline processor shares the name but not the timeout of the callback processor
+	err = manager.AddInlineProcessors(InlineProcessor{
+		Name: "hang",
+		Processor: func(ctx context.Context, _, _ string, _, _ int64) (InOutData, string, error) {
+			select {
+			case <-time.After(30 * time.Millisecond):
+				return nil, "", nil
+			case <-ctx.Done():
+				return nil, "", ctx.Err()
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err = manager.ProcessMsg(ctx, Actor{UserID: 1}, 10, 1, "hang"+inlineDivider+" query"); err != nil {
+		t.Error("inline processor must not get the callback processor timeout, got", err)
+	}
+}
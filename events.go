@@ -0,0 +1,149 @@
+package tgmanager
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TransitionEvent describes a single move between nodes. From is empty for nodes sent with SendNode,
+// To is empty when the flow is closed.
+type TransitionEvent struct {
+	From         string            `json:"from"`
+	To           string            `json:"to"`
+	ButtonLabel  string            `json:"button_label,omitempty"`
+	CallbackType string            `json:"callback_type,omitempty"`
+	UserID       int64             `json:"user_id"`
+	ChatID       int64             `json:"chat_id"`
+	Time         time.Time         `json:"time"`
+	Latency      time.Duration     `json:"latency"`
+	Outcome      TransitionOutcome `json:"outcome"`
+	Error        string            `json:"error,omitempty"`
+}
+
+// EventSink receives transition events, it is called synchronously and should be fast.
+type EventSink interface {
+	Emit(ctx context.Context, event TransitionEvent) error
+}
+
+// SetEventSink enables transition events, nil disables them.
+func (c *callbackManager) SetEventSink(sink EventSink) {
+	c.eventSink = sink
+}
+
+// emitTransition completes the event with the result of the operation and passes it to the sink.
+func (c *callbackManager) emitTransition(ctx context.Context, event TransitionEvent, err error) {
+	if c.eventSink == nil {
+		return
+	}
+	event.Latency = time.Since(event.Time)
+	switch {
+	case errors.Is(err, ErrNotMenuOwner), errors.Is(err, ErrProcessorForbidden), errors.Is(err, ErrRateLimited):
+		event.Outcome = TransitionOutcomeRejected
+	case err != nil:
+		event.Outcome = TransitionOutcomeFailed
+	case event.Outcome == "":
+		event.Outcome = TransitionOutcomeSuccess
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	if emitErr := c.eventSink.Emit(ctx, event); emitErr != nil {
+		c.logError(ctx, "emit transition", fmt.Errorf("emitting transition: %w", emitErr))
+	}
+}
+
+// JSONLSink writes events as JSON lines.
+type JSONLSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	return &JSONLSink{w: w}
+}
+
+// OpenJSONLFile appends events to the file at path creating it when missing.
+func OpenJSONLFile(path string) (*JSONLSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening events file: %w", err)
+	}
+	return &JSONLSink{w: file, closer: file}, nil
+}
+
+func (s *JSONLSink) Emit(_ context.Context, event TransitionEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("json marshal: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the file opened by OpenJSONLFile.
+func (s *JSONLSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// ChannelSink passes events to a buffered channel, events are dropped when it is full
+// so a slow consumer never blocks the bot.
+type ChannelSink struct {
+	events  chan TransitionEvent
+	dropped atomic.Int64
+}
+
+func NewChannelSink(size int) *ChannelSink {
+	return &ChannelSink{events: make(chan TransitionEvent, size)}
+}
+
+func (s *ChannelSink) Emit(_ context.Context, event TransitionEvent) error {
+	select {
+	case s.events <- event:
+	default:
+		s.dropped.Add(1)
+	}
+	return nil
+}
+
+func (s *ChannelSink) Events() <-chan TransitionEvent {
+	return s.events
+}
+
+// Dropped returns the number of events lost because the channel was full.
+func (s *ChannelSink) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// ReadTransitionEvents reads events written by JSONLSink.
+func ReadTransitionEvents(r io.Reader) ([]TransitionEvent, error) {
+	var events []TransitionEvent
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var event TransitionEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("line %d: json unmarshal: %w", line, err)
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading events: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,109 @@
+package tgmanager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_transitionEvents(t *testing.T) {
+	sink := NewChannelSink(10)
+	manager := newTestConsistencyManager(t, &testStorage{}, &testDeleteSender{})
+	manager.SetEventSink(sink)
+
+	ctx := context.Background()
+	if err := manager.SendNode(ctx, NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "start"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := manager.ProcessCallback(ctx, Actor{UserID: 5}, "", 1, 1, "start>0>0"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	_ = manager.ProcessCallback(ctx, Actor{UserID: 5}, "", 1, 1, "start>0>3")
+
+	for _, expected := range []TransitionEvent{
+		{To: "start", ChatID: 1, Outcome: TransitionOutcomeSuccess},
+		{From: "start", To: "start", ButtonLabel: "next", CallbackType: "process", UserID: 5, ChatID: 1, Outcome: TransitionOutcomeSuccess},
+		{From: "start", CallbackType: "process", UserID: 5, ChatID: 1, Outcome: TransitionOutcomeFailed},
+	} {
+		event := <-sink.Events()
+		if event.Time.IsZero() {
+			t.Error("event time is not set")
+		}
+		event.Time, event.Latency, event.Error = time.Time{}, 0, ""
+		if !reflect.DeepEqual(event, expected) {
+			t.Error("event non match", "actual:", event, "expected:", expected)
+		}
+	}
+}
+
+func Test_transitionEvents_rateLimited(t *testing.T) {
+	sink := NewChannelSink(10)
+	manager := newTestConsistencyManager(t, &testStorage{}, &testDeleteSender{})
+	manager.SetEventSink(sink)
+	manager.SetRateLimit(RateLimitConfig{UserRate: Rate{Burst: 1, Interval: time.Hour}})
+
+	ctx := context.Background()
+	if err := manager.SendNode(ctx, NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "start"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	<-sink.Events()
+	if err := manager.ProcessCallback(ctx, Actor{UserID: 5}, "", 1, 1, "start>0>0"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	<-sink.Events()
+	if err := manager.ProcessCallback(ctx, Actor{UserID: 5}, "", 1, 1, "start>0>0"); !errors.Is(err, ErrRateLimited) {
+		t.Fatal("expected rate limit error, got", err)
+	}
+
+	select {
+	case event := <-sink.Events():
+		if event.Outcome != TransitionOutcomeRejected || event.UserID != 5 || event.ChatID != 1 {
+			t.Error("unexpected event of the throttled callback:", event)
+		}
+	default:
+		t.Error("throttled callback must emit an event")
+	}
+}
+
+func Test_BuildFunnel(t *testing.T) {
+	start := time.Now()
+	var buf bytes.Buffer
+	sink := NewJSONLSink(&buf)
+	for i, item := range []struct {
+		user int64
+		to   string
+	}{
+		{1, "catalog"}, {2, "catalog"}, {3, "catalog"},
+		{1, "item"}, {2, "item"}, {1, "help"},
+		{1, "checkout"}, {3, "help"},
+	} {
+		event := TransitionEvent{UserID: item.user, To: item.to, Time: start.Add(time.Duration(i) * time.Second), Outcome: TransitionOutcomeSuccess}
+		if err := sink.Emit(context.Background(), event); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	events, err := ReadTransitionEvents(&buf)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	report := BuildFunnel(events, "catalog", "item", "checkout")
+	expected := []FunnelStep{
+		{Processor: "catalog", Users: 3, Conversion: 1},
+		{Processor: "item", Users: 2, DropOff: 1, Conversion: 2.0 / 3},
+		{Processor: "checkout", Users: 1, DropOff: 1, Conversion: 1.0 / 3},
+	}
+	if !reflect.DeepEqual(report.Steps, expected) {
+		t.Error("funnel non match", "actual:", report.Steps, "expected:", expected)
+	}
+
+	dropOffs := BuildDropOffs(events)
+	expectedDropOffs := []DropOff{{Processor: "checkout", Users: 1}, {Processor: "help", Users: 1}, {Processor: "item", Users: 1}}
+	if !reflect.DeepEqual(dropOffs, expectedDropOffs) {
+		t.Error("drop offs non match", "actual:", dropOffs, "expected:", expectedDropOffs)
+	}
+}
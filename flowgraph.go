@@ -0,0 +1,335 @@
+package tgmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FlowEdge is a button leading from the node rendered by From to the processor To.
+type FlowEdge struct {
+	From  string
+	To    string
+	Label string
+	Type  CallbackProcessorType
+}
+
+// FlowGraph is the menu graph built from registered processors and observed nodes.
+type FlowGraph struct {
+	// Processors are all registered processors
+	Processors []string
+	// Entries are processors sent with SendNode or used as crawl roots
+	Entries []string
+	// Rendered are processors whose nodes were observed
+	Rendered []string
+	Edges    []FlowEdge
+}
+
+// flowRecorder collects edges of nodes sent at runtime or during a crawl.
+type flowRecorder struct {
+	mu       sync.Mutex
+	entries  map[string]struct{}
+	rendered map[string]struct{}
+	edges    map[FlowEdge]struct{}
+}
+
+func newFlowRecorder() *flowRecorder {
+	return &flowRecorder{
+		entries:  make(map[string]struct{}),
+		rendered: make(map[string]struct{}),
+		edges:    make(map[FlowEdge]struct{}),
+	}
+}
+
+func (r *flowRecorder) entry(processor string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[processor] = struct{}{}
+}
+
+// record adds the buttons of the node, it returns the edges leading to processors.
+// A node without a processor can't be placed in the graph and is skipped.
+func (r *flowRecorder) record(data *inOutData) []FlowEdge {
+	if data.Processor == "" {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rendered[data.Processor] = struct{}{}
+	var edges []FlowEdge
+	for _, node := range append(append([]nextNode{}, data.ProcessorNodes...), data.MenuNodes...) {
+		defNode := node.getDefault()
+		if defNode == nil || defNode.getProcessorName() == "" {
+			continue
+		}
+		edge := FlowEdge{
+			From:  data.Processor,
+			To:    defNode.getProcessorName(),
+			Label: node.getButtonLabel(),
+			Type:  defNode.getProcessorType(),
+		}
+		r.edges[edge] = struct{}{}
+		edges = append(edges, edge)
+	}
+	return edges
+}
+
+// mergeInto copies the recorded nodes to the maps.
+func (r *flowRecorder) mergeInto(entries, rendered map[string]struct{}, edges map[FlowEdge]struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for processor := range r.entries {
+		entries[processor] = struct{}{}
+	}
+	for processor := range r.rendered {
+		rendered[processor] = struct{}{}
+	}
+	for edge := range r.edges {
+		edges[edge] = struct{}{}
+	}
+}
+
+// SetFlowRecording makes the manager record the buttons of every sent node for FlowGraph.
+// Buttons with dynamic labels grow the graph, so it is meant for staging and tests.
+func (c *callbackManager) SetFlowRecording(enabled bool) {
+	if !enabled {
+		c.flowRecording = nil
+		return
+	}
+	if c.flowRecording == nil {
+		c.flowRecording = newFlowRecorder()
+	}
+}
+
+func (c *callbackManager) recordFlow(data InOutData) {
+	if c.flowRecording == nil {
+		return
+	}
+	if dataStruct, ok := data.(*inOutData); ok {
+		c.flowRecording.record(dataStruct)
+	}
+}
+
+// Crawl records the graph by invoking processors with empty data starting from entries and
+// following their buttons. Processors run without a user and may have side effects, so it is
+// meant for tests. Processor failures are returned joined, the crawl goes on.
+// The crawl keeps its own records, it doesn't turn on the recording of sent nodes.
+func (c *callbackManager) Crawl(ctx context.Context, entries ...string) error {
+	if c.crawlRecording == nil {
+		c.crawlRecording = newFlowRecorder()
+	}
+	recorder := c.crawlRecording
+
+	type crawlItem struct {
+		processor string
+		payload   []byte
+	}
+	queue := make([]crawlItem, 0, len(entries))
+	visited := make(map[string]struct{})
+	for _, entry := range entries {
+		recorder.entry(entry)
+		queue = append(queue, crawlItem{processor: entry})
+		visited[entry] = struct{}{}
+	}
+
+	var errs []error
+	for len(queue) != 0 {
+		item := queue[0]
+		queue = queue[1:]
+
+		processor, ok := c.allProcessors[item.processor]
+		if !ok || processor == nil {
+			continue
+		}
+
+		data := &inOutData{AppearType: c.defaultAppearType, ExternalPayload: item.payload}
+		var newData InOutData
+		err := c.runProcessor(ctx, item.processor, c.callbackTimeout(item.processor), func(ctx context.Context) (err error) {
+			newData, err = processor(ctx, data)
+			return err
+		})
+		if err != nil {
+			errs = append(errs, &ManagerError{Op: "crawl", Processor: item.processor, Err: err})
+			continue
+		}
+		if newData == nil {
+			newData = data
+		}
+		dataStruct, ok := newData.(*inOutData)
+		if !ok {
+			continue
+		}
+		dataStruct.Processor = item.processor
+
+		for _, edge := range recorder.record(dataStruct) {
+			if _, ok = visited[edge.To]; ok {
+				continue
+			}
+			visited[edge.To] = struct{}{}
+			queue = append(queue, crawlItem{processor: edge.To, payload: edgePayload(dataStruct, edge)})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func edgePayload(data *inOutData, edge FlowEdge) []byte {
+	for _, node := range append(append([]nextNode{}, data.ProcessorNodes...), data.MenuNodes...) {
+		if defNode := node.getDefault(); defNode != nil && defNode.getProcessorName() == edge.To {
+			return defNode.getExternalPayload()
+		}
+	}
+	return nil
+}
+
+// FlowGraph returns the graph of registered processors, recorded and crawled nodes.
+func (c *callbackManager) FlowGraph() FlowGraph {
+	graph := FlowGraph{Processors: sortedKeys(c.allProcessors)}
+	if c.flowRecording == nil && c.crawlRecording == nil {
+		return graph
+	}
+
+	entries, rendered, edges := make(map[string]struct{}), make(map[string]struct{}), make(map[FlowEdge]struct{})
+	for _, recorder := range []*flowRecorder{c.flowRecording, c.crawlRecording} {
+		if recorder != nil {
+			recorder.mergeInto(entries, rendered, edges)
+		}
+	}
+	graph.Entries = sortedKeys(entries)
+	graph.Rendered = sortedKeys(rendered)
+	for edge := range edges {
+		graph.Edges = append(graph.Edges, edge)
+	}
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		a, b := graph.Edges[i], graph.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Label < b.Label
+	})
+	return graph
+}
+
+// DeadEnds returns rendered processors without buttons leading anywhere.
+func (g FlowGraph) DeadEnds() []string {
+	outgoing := make(map[string]struct{})
+	for _, edge := range g.Edges {
+		outgoing[edge.From] = struct{}{}
+	}
+	var out []string
+	for _, processor := range g.Rendered {
+		if _, ok := outgoing[processor]; !ok {
+			out = append(out, processor)
+		}
+	}
+	return out
+}
+
+// Unreachable returns registered processors which are neither entries nor targets of any button.
+func (g FlowGraph) Unreachable() []string {
+	reachable := make(map[string]struct{})
+	for _, entry := range g.Entries {
+		reachable[entry] = struct{}{}
+	}
+	for _, edge := range g.Edges {
+		reachable[edge.To] = struct{}{}
+	}
+	var out []string
+	for _, processor := range g.Processors {
+		if _, ok := reachable[processor]; !ok {
+			out = append(out, processor)
+		}
+	}
+	return out
+}
+
+// nodes returns all processors of the graph including unregistered button targets.
+func (g FlowGraph) nodes() []string {
+	set := make(map[string]struct{})
+	for _, list := range [][]string{g.Processors, g.Entries, g.Rendered} {
+		for _, processor := range list {
+			set[processor] = struct{}{}
+		}
+	}
+	for _, edge := range g.Edges {
+		set[edge.From] = struct{}{}
+		set[edge.To] = struct{}{}
+	}
+	return sortedKeys(set)
+}
+
+func (e FlowEdge) label() string {
+	if e.Type == CallbackProcessorTypeProcess {
+		return e.Label
+	}
+	return fmt.Sprintf("%s (%s)", e.Label, e.Type)
+}
+
+// Mermaid renders the graph as a mermaid flowchart, dead ends and unreachable processors
+// are highlighted with the deadEnd and unreachable classes.
+func (g FlowGraph) Mermaid() string {
+	ids := make(map[string]string)
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	for i, processor := range g.nodes() {
+		ids[processor] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(&b, "    %s[%s]\n", ids[processor], mermaidQuote(processor))
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "    %s -->|%s| %s\n", ids[edge.From], mermaidQuote(edge.label()), ids[edge.To])
+	}
+
+	b.WriteString("    classDef deadEnd fill:#fde2e1,stroke:#d33\n")
+	b.WriteString("    classDef unreachable fill:#eee,stroke:#999,stroke-dasharray:4\n")
+	for _, item := range []struct {
+		class      string
+		processors []string
+	}{{"deadEnd", g.DeadEnds()}, {"unreachable", g.Unreachable()}} {
+		class, processors := item.class, item.processors
+		if len(processors) == 0 {
+			continue
+		}
+		classIDs := make([]string, 0, len(processors))
+		for _, processor := range processors {
+			classIDs = append(classIDs, ids[processor])
+		}
+		fmt.Fprintf(&b, "    class %s %s\n", strings.Join(classIDs, ","), class)
+	}
+	return b.String()
+}
+
+// DOT renders the graph in the graphviz format, dead ends are red and unreachable processors are grey.
+func (g FlowGraph) DOT() string {
+	styles := make(map[string]string)
+	for _, processor := range g.DeadEnds() {
+		styles[processor] = ` style=filled fillcolor="#fde2e1" color="#dd3333"`
+	}
+	for _, processor := range g.Unreachable() {
+		styles[processor] = ` style="filled,dashed" fillcolor="#eeeeee" color="#999999"`
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph flow {\n")
+	for _, processor := range g.nodes() {
+		fmt.Fprintf(&b, "    %s [label=%s%s];\n", dotQuote(processor), dotQuote(processor), styles[processor])
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "    %s -> %s [label=%s];\n", dotQuote(edge.From), dotQuote(edge.To), dotQuote(edge.label()))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
@@ -0,0 +1,115 @@
+package tgmanager
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func newTestFlowManager(t *testing.T) CallbackManager {
+	manager, err := NewCallbackManager("default", CallBackAppearTypeUpdate, nil, &testStorage{}, &testDeleteSender{}, nil, nil, nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	t.Cleanup(func() { _ = manager.Close(context.Background()) })
+	err = manager.AddProcessors(Processor{
+		Name: "menu",
+		Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+			data.AddNode(NewDefaultNode("Catalog", "catalog", CallbackProcessorTypeProcess, nil))
+			return data, nil
+		},
+	}, Processor{
+		Name: "catalog",
+		Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+			data.AddNode(NewDefaultNode("Item", "item", CallbackProcessorTypeProcess, nil))
+			data.AddNode(NewDefaultNode("Back", "menu", CallbackProcessorTypeBack, nil))
+			return data, nil
+		},
+	}, Processor{
+		Name: "item",
+		Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+			return data, nil
+		},
+	}, Processor{
+		Name: "legacy",
+		Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+			return data, nil
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	return manager
+}
+
+func Test_FlowGraph(t *testing.T) {
+	manager := newTestFlowManager(t)
+	if err := manager.Crawl(context.Background(), "menu"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	graph := manager.FlowGraph()
+
+	mermaid := `flowchart TD
+    n0["catalog"]
+    n1["item"]
+    n2["legacy"]
+    n3["menu"]
+    n0 -->|"Item"| n1
+    n0 -->|"Back (back)"| n3
+    n3 -->|"Catalog"| n0
+    classDef deadEnd fill:#fde2e1,stroke:#d33
+    classDef unreachable fill:#eee,stroke:#999,stroke-dasharray:4
+    class n1 deadEnd
+    class n2 unreachable
+`
+	if actual := graph.Mermaid(); actual != mermaid {
+		t.Error("mermaid non match", "actual:\n", actual, "expected:\n", mermaid)
+	}
+
+	dot := `digraph flow {
+    "catalog" [label="catalog"];
+    "item" [label="item" style=filled fillcolor="#fde2e1" color="#dd3333"];
+    "legacy" [label="legacy" style="filled,dashed" fillcolor="#eeeeee" color="#999999"];
+    "menu" [label="menu"];
+    "catalog" -> "item" [label="Item"];
+    "catalog" -> "menu" [label="Back (back)"];
+    "menu" -> "catalog" [label="Catalog"];
+}
+`
+	if actual := graph.DOT(); actual != dot {
+		t.Error("dot non match", "actual:\n", actual, "expected:\n", dot)
+	}
+}
+
+func Test_Crawl_recording(t *testing.T) {
+	for _, tCase := range []struct {
+		name      string
+		recording bool
+		rendered  []string
+	}{
+		{name: "crawl only", rendered: []string{"catalog", "item", "menu"}},
+		{name: "crawl and recording", recording: true, rendered: []string{"catalog", "item", "legacy", "menu"}},
+	} {
+		manager := newTestFlowManager(t)
+		manager.SetFlowRecording(tCase.recording)
+		ctx := context.Background()
+		if err := manager.Crawl(ctx, "menu"); err != nil {
+			t.Fatal(tCase.name, "unexpected error:", err)
+		}
+		if err := manager.SendNode(ctx, NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "legacy"); err != nil {
+			t.Fatal(tCase.name, "unexpected error:", err)
+		}
+		if rendered := manager.FlowGraph().Rendered; !reflect.DeepEqual(rendered, tCase.rendered) {
+			t.Error(tCase.name, "rendered non match", "actual:", rendered, "expected:", tCase.rendered)
+		}
+	}
+}
+
+func Test_flowRecorder_noProcessor(t *testing.T) {
+	recorder := newFlowRecorder()
+	data := &inOutData{}
+	data.AddNode(NewDefaultNode("Menu", "menu", CallbackProcessorTypeProcess, nil))
+	if edges := recorder.record(data); len(edges) != 0 || len(recorder.rendered) != 0 || len(recorder.edges) != 0 {
+		t.Error("node without a processor must not be recorded", "edges:", edges, "rendered:", recorder.rendered)
+	}
+}
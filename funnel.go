@@ -0,0 +1,100 @@
+package tgmanager
+
+import (
+	"sort"
+)
+
+type FunnelStep struct {
+	Processor string
+	// Users reached the step after passing all previous ones
+	Users int
+	// DropOff is the number of users who reached the previous step but not this one
+	DropOff int
+	// Conversion is the share of users of the first step who reached this one
+	Conversion float64
+}
+
+type FunnelReport struct {
+	Steps []FunnelStep
+}
+
+// DropOff is the number of users whose last successful transition ended at the processor.
+type DropOff struct {
+	Processor string
+	Users     int
+}
+
+// BuildFunnel counts users reaching the processors in the given order, other transitions
+// between the steps are allowed. Users are told apart by user id, by chat id when it is unknown.
+func BuildFunnel(events []TransitionEvent, steps ...string) FunnelReport {
+	progress := make(map[int64]int)
+	for _, event := range sortedSuccessful(events) {
+		user := eventUser(event)
+		reached := progress[user]
+		if reached < len(steps) && event.To == steps[reached] {
+			progress[user] = reached + 1
+		}
+	}
+
+	report := FunnelReport{Steps: make([]FunnelStep, len(steps))}
+	for i, step := range steps {
+		report.Steps[i].Processor = step
+		for _, reached := range progress {
+			if reached > i {
+				report.Steps[i].Users++
+			}
+		}
+		if i > 0 {
+			report.Steps[i].DropOff = report.Steps[i-1].Users - report.Steps[i].Users
+		}
+		if first := report.Steps[0].Users; first > 0 {
+			report.Steps[i].Conversion = float64(report.Steps[i].Users) / float64(first)
+		}
+	}
+	return report
+}
+
+// BuildDropOffs returns where users stopped, sorted by the number of users.
+// Users who closed the flow are not counted.
+func BuildDropOffs(events []TransitionEvent) []DropOff {
+	last := make(map[int64]string)
+	for _, event := range sortedSuccessful(events) {
+		last[eventUser(event)] = event.To
+	}
+
+	counts := make(map[string]int)
+	for _, processor := range last {
+		if processor != "" {
+			counts[processor]++
+		}
+	}
+
+	dropOffs := make([]DropOff, 0, len(counts))
+	for _, processor := range sortedKeys(counts) {
+		dropOffs = append(dropOffs, DropOff{Processor: processor, Users: counts[processor]})
+	}
+	sort.SliceStable(dropOffs, func(i, j int) bool {
+		return dropOffs[i].Users > dropOffs[j].Users
+	})
+	return dropOffs
+}
+
+func sortedSuccessful(events []TransitionEvent) []TransitionEvent {
+	out := make([]TransitionEvent, 0, len(events))
+	for _, event := range events {
+		if event.Outcome == TransitionOutcomeSuccess || event.Outcome == TransitionOutcomeClosed {
+			out = append(out, event)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Time.Before(out[j].Time)
+	})
+	return out
+}
+
+func eventUser(event TransitionEvent) int64 {
+	if event.UserID != 0 {
+		return event.UserID
+	}
+	return event.ChatID
+}
@@ -0,0 +1,84 @@
+package tgmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+)
+
+// PanicError is returned instead of crashing when a processor panics.
+type PanicError struct {
+	Processor string
+	Value     any
+	Stack     []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("processor %s panicked: %v", e.Processor, e.Value)
+}
+
+func (e *PanicError) Unwrap() []error {
+	if err, ok := e.Value.(error); ok {
+		return []error{ErrProcessorPanic, err}
+	}
+	return []error{ErrProcessorPanic}
+}
+
+// SetProcessorTimeout sets the default time a processor may run, Processor.Timeout and
+// InlineProcessor.Timeout override it. Zero, the default, disables the timeout.
+func (c *callbackManager) SetProcessorTimeout(timeout time.Duration) {
+	c.processorTimeout = timeout
+}
+
+// callbackTimeout returns the timeout of the callback processor.
+func (c *callbackManager) callbackTimeout(name string) time.Duration {
+	if timeout, ok := c.processorTimeouts[name]; ok {
+		return timeout
+	}
+	return c.processorTimeout
+}
+
+// inlineTimeout returns the timeout of the inline processor.
+func (c *callbackManager) inlineTimeout(name string) time.Duration {
+	if timeout, ok := c.inlineProcessorTimeouts[name]; ok {
+		return timeout
+	}
+	return c.processorTimeout
+}
+
+// runProcessor calls the processor with a deadline in its context and recovers its panics.
+// The processor runs in the caller goroutine, so it must honor the context to be stopped in time.
+func (c *callbackManager) runProcessor(ctx context.Context, name string, timeout time.Duration, run func(ctx context.Context) error) (err error) {
+	defer c.observeSince(MetricProcessorSeconds, time.Now(), Labels{metricLabelProcessor: name})
+	ctx, span := c.startSpan(ctx, "tgmanager.processor", StringAttribute(AttrProcessor, name))
+	defer func() { endSpan(span, err) }()
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout,
+			fmt.Errorf("%w: processor %s exceeded %s", ErrProcessorTimeout, name, timeout))
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			stack := debug.Stack()
+			c.logAttrs(ctx, slog.LevelError, "processor panic",
+				slog.String(logKeyProcessor, name),
+				slog.Any("panic", r),
+				slog.String("stack", string(stack)),
+			)
+			err = &PanicError{Processor: name, Value: r, Stack: stack}
+		}
+	}()
+
+	err = run(ctx)
+	// a processor stopped by the deadline reports the timeout
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil && errors.Is(err, ctxErr) {
+		err = context.Cause(ctx)
+	}
+	return err
+}
@@ -1,7 +1,7 @@
 package tgmanager
 
 import (
-	"errors"
+	"fmt"
 )
 
 func NewInOutData(chatID, messageID int64, message string, appearType CallBackAppearType, nodes ...NextNode) InOutData {
@@ -21,30 +21,71 @@ type InOutData interface {
 	AddNode(node NextNode)
 	SetMsg(msg string)
 	GetMsg() string
+	SetParseMode(mode ParseMode)
+	GetParseMode() ParseMode
+	SetMedia(media *Media)
+	GetMedia() *Media
+	SetAlbum(items ...*Media)
+	GetAlbum() []*Media
+	AddMessages(items ...InOutData)
+	SetCallbackAnswer(answer CallbackAnswer)
 	GetChatID() int64
+	GetActor() Actor
+	GetOwnerID() int64
+	SetOwnerID(userID int64)
+	SetAccess(access NodeAccess)
 	GetPayload() []byte
 	SetPayload(in []byte)
 	getMsgID() int64
-	generateTelegramContainer() (TelegramContainer, error)
+	getMessages() []InOutData
+	getCallbackAnswer() CallbackAnswer
+	setActor(actor Actor)
+	setOwnerID(userID int64)
+	setDefaultAccess(access NodeAccess)
+	generateTelegramContainer(allowed func(processor string) bool) (TelegramContainer, error)
 	setMsgID(msgID int64)
+	setPartMsgIDs(msgIDs []int64)
+	setAlbumMsgIDs(msgIDs []int64)
 	getAppearType() CallBackAppearType
 	setDefaultMessage(in string)
 	setAppearType(in CallBackAppearType)
+	getProcessor() string
+	setProcessor(name string)
 }
 type inOutData struct {
 	ChatID          int64
+	Processor       string
+	Actor           Actor
+	OwnerID         int64
+	Access          NodeAccess
 	MessageID       int64
+	PartMessageIDs  []int64
+	AlbumMessageIDs []int64
 	Message         string
+	ParseMode       ParseMode
+	Media           *Media
+	Album           []*Media
 	ExternalPayload []byte
 	AppearType      CallBackAppearType
 	ProcessorNodes  []nextNode
 	MenuNodes       []nextNode
+	Messages        []InOutData    `json:"-"`
+	CallbackAnswer  CallbackAnswer `json:"-"`
 }
 
 func (i *inOutData) setAppearType(in CallBackAppearType) {
 	i.AppearType = in
 }
 
+// getProcessor returns the processor which rendered the node.
+func (i *inOutData) getProcessor() string {
+	return i.Processor
+}
+
+func (i *inOutData) setProcessor(name string) {
+	i.Processor = name
+}
+
 func (i *inOutData) AddNode(node NextNode) {
 	if node == nil {
 		return
@@ -88,10 +129,85 @@ func (i *inOutData) GetMsg() string {
 	return i.Message
 }
 
+func (i *inOutData) SetParseMode(mode ParseMode) {
+	i.ParseMode = mode
+}
+
+func (i *inOutData) GetParseMode() ParseMode {
+	return i.ParseMode
+}
+
+func (i *inOutData) SetMedia(media *Media) {
+	i.Media = media
+}
+
+func (i *inOutData) GetMedia() *Media {
+	return i.Media
+}
+
+func (i *inOutData) SetAlbum(items ...*Media) {
+	i.Album = items
+}
+
+func (i *inOutData) GetAlbum() []*Media {
+	return i.Album
+}
+
+// AddMessages attaches messages sent after this one, e.g. a notification to another chat.
+// Their recipients are unknown, so buttons of processors requiring roles are hidden in them
+// and their nodes are shared unless SetAccess is called on them.
+func (i *inOutData) AddMessages(items ...InOutData) {
+	i.Messages = append(i.Messages, items...)
+}
+
+func (i *inOutData) getMessages() []InOutData {
+	return i.Messages
+}
+
+func (i *inOutData) SetCallbackAnswer(answer CallbackAnswer) {
+	i.CallbackAnswer = answer
+}
+
+func (i *inOutData) getCallbackAnswer() CallbackAnswer {
+	return i.CallbackAnswer
+}
+
 func (i *inOutData) GetChatID() int64 {
 	return i.ChatID
 }
 
+// GetActor returns the user whose action produced the data.
+func (i *inOutData) GetActor() Actor {
+	return i.Actor
+}
+
+// setActor replaces the actor even with an empty one, roles must never come from a previous presser.
+func (i *inOutData) setActor(actor Actor) {
+	i.Actor = actor
+}
+
+// GetOwnerID returns the user who opened the menu.
+func (i *inOutData) GetOwnerID() int64 {
+	return i.OwnerID
+}
+
+// SetOwnerID sets the user allowed to press buttons of owner only nodes, e.g. for menus sent
+// without an actor in the context.
+func (i *inOutData) SetOwnerID(userID int64) {
+	i.OwnerID = userID
+}
+
+func (i *inOutData) setOwnerID(userID int64) {
+	if i.OwnerID == 0 {
+		i.OwnerID = userID
+	}
+}
+
+// SetAccess overrides the manager wide owner only setting for this node.
+func (i *inOutData) SetAccess(access NodeAccess) {
+	i.Access = access
+}
+
 func (i *inOutData) GetPayload() []byte {
 	return i.ExternalPayload
 }
@@ -103,19 +219,53 @@ func (i *inOutData) setMsgID(msgID int64) {
 	i.MessageID = msgID
 }
 
+func (i *inOutData) setPartMsgIDs(msgIDs []int64) {
+	i.PartMessageIDs = msgIDs
+}
+
+func (i *inOutData) setAlbumMsgIDs(msgIDs []int64) {
+	i.AlbumMessageIDs = msgIDs
+}
+
 func (i *inOutData) getMsgID() int64 {
 	return i.MessageID
 }
 
+// setDefaultAccess sets the access unless the node has its own.
+func (i *inOutData) setDefaultAccess(access NodeAccess) {
+	if i.Access == "" {
+		i.Access = access
+	}
+}
+
 func (i *inOutData) getAppearType() CallBackAppearType {
 	return i.AppearType
 }
 
-func (i *inOutData) generateTelegramContainer() (TelegramContainer, error) {
+// generateTelegramContainer builds the message, buttons of processors rejected by allowed are hidden.
+// A nil allowed shows every button.
+func (i *inOutData) generateTelegramContainer(allowed func(processor string) bool) (TelegramContainer, error) {
+	if err := validateMarkup(i.Message, i.ParseMode); err != nil {
+		return TelegramContainer{}, err
+	}
+	if i.Media != nil {
+		if err := i.Media.validate(); err != nil {
+			return TelegramContainer{}, err
+		}
+	}
+	if len(i.Album) != 0 {
+		if err := validateAlbum(i.Album); err != nil {
+			return TelegramContainer{}, err
+		}
+	}
+
 	var tgContainer TelegramContainer
 	tgContainer.Buttons = make([]Button, 0, len(i.MenuNodes)+len(i.ProcessorNodes))
 	tgContainer.ChatID = i.ChatID
+	tgContainer.ThreadID = i.Actor.ThreadID
 	tgContainer.Message = i.Message
+	tgContainer.ParseMode = i.ParseMode
+	tgContainer.Media = i.Media
 	tgContainer.OldMessageID = i.MessageID
 	tgContainer.AppearType = i.AppearType
 
@@ -125,6 +275,9 @@ func (i *inOutData) generateTelegramContainer() (TelegramContainer, error) {
 		inlNode := i.ProcessorNodes[j].InlineNode
 
 		if defNode != nil {
+			if allowed != nil && !allowed(defNode.getProcessorName()) {
+				continue
+			}
 			tgContainer.Buttons = append(tgContainer.Buttons, Button{
 				ButtonLabel:   i.ProcessorNodes[j].getButtonLabel(),
 				Callback:      defNode.callback(),
@@ -145,7 +298,7 @@ func (i *inOutData) generateTelegramContainer() (TelegramContainer, error) {
 
 	for j := range i.MenuNodes {
 		defNode := i.MenuNodes[j].DefaultNode
-		if defNode == nil {
+		if defNode == nil || (allowed != nil && !allowed(defNode.getProcessorName())) {
 			continue
 		}
 		tgContainer.Buttons = append(tgContainer.Buttons, Button{
@@ -158,9 +311,33 @@ func (i *inOutData) generateTelegramContainer() (TelegramContainer, error) {
 	return tgContainer, nil
 }
 
+// replaced describes the message currently shown for the state before it is replaced by a new one.
+func (i *inOutData) replaced() replacedMessage {
+	if i == nil {
+		return replacedMessage{}
+	}
+	return replacedMessage{
+		partIDs:  i.PartMessageIDs,
+		albumIDs: i.AlbumMessageIDs,
+		// a media with a long caption is sent as a leading part, so the keyboard message is text then
+		hasMedia: i.Media != nil && len(i.PartMessageIDs) == 0,
+	}
+}
+
+type replacedMessage struct {
+	partIDs  []int64
+	albumIDs []int64
+	hasMedia bool
+}
+
+// linkedIDs returns the messages shown together with the keyboard message.
+func (r replacedMessage) linkedIDs() []int64 {
+	return append(append([]int64{}, r.albumIDs...), r.partIDs...)
+}
+
 func (i *inOutData) getProcessorNodeByIndex(idx int64) (nextNode, error) {
 	if int(idx) > len(i.ProcessorNodes)-1 {
-		return nextNode{}, errors.New("invalid idx")
+		return nextNode{}, fmt.Errorf("%w: index %d of %d nodes", ErrStaleIndex, idx, len(i.ProcessorNodes))
 	}
 	return i.ProcessorNodes[idx], nil
 }
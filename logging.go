@@ -0,0 +1,64 @@
+package tgmanager
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+const (
+	logKeyChatID       = "chat_id"
+	logKeyMessageID    = "message_id"
+	logKeyProcessor    = "processor"
+	logKeyCallbackType = "callback_type"
+	logKeyAppearType   = "appear_type"
+	logKeyDuration     = "duration"
+)
+
+// logErrorFunc adapts a function to the logger interface.
+type logErrorFunc func(err error)
+
+func (f logErrorFunc) LogError(err error) {
+	f(err)
+}
+
+// SetSlogLogger enables structured logging, the legacy logger keeps receiving errors.
+func (c *callbackManager) SetSlogLogger(logger *slog.Logger) {
+	c.slogger = logger
+}
+
+func (c *callbackManager) logAttrs(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if c.slogger != nil {
+		c.slogger.LogAttrs(ctx, level, msg, attrs...)
+	}
+}
+
+func (c *callbackManager) logError(ctx context.Context, msg string, err error, attrs ...slog.Attr) {
+	if c.logger != nil {
+		c.logger.LogError(err)
+	}
+	c.logAttrs(ctx, slog.LevelError, msg, append(attrs, slog.Any("error", err))...)
+}
+
+// logResult logs a finished operation at info level or at error level when it failed.
+// Failures caused by users are expected in normal use and stay at info level, messages
+// which are not meant for the bot at debug level.
+func (c *callbackManager) logResult(ctx context.Context, msg string, start time.Time, err error, attrs ...slog.Attr) {
+	if c.slogger == nil {
+		return
+	}
+	attrs = append(attrs, slog.Duration(logKeyDuration, time.Since(start)))
+	if err != nil {
+		level := slog.LevelError
+		switch {
+		case errors.Is(err, ErrMessageProcessorNotFound):
+			level = slog.LevelDebug
+		case IsUserError(err):
+			level = slog.LevelInfo
+		}
+		c.slogger.LogAttrs(ctx, level, msg, append(attrs, slog.Any("error", err))...)
+		return
+	}
+	c.slogger.LogAttrs(ctx, slog.LevelInfo, msg, attrs...)
+}
@@ -0,0 +1,113 @@
+package tgmanager
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+// testLogHandler captures records with their attributes.
+type testLogHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *testLogHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *testLogHandler) Handle(_ context.Context, record slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, record)
+	return nil
+}
+
+func (h *testLogHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *testLogHandler) WithGroup(string) slog.Handler      { return h }
+
+// find returns the last record with the message and its attributes.
+func (h *testLogHandler) find(msg string) (slog.Record, map[string]slog.Value, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for j := len(h.records) - 1; j >= 0; j-- {
+		if h.records[j].Message != msg {
+			continue
+		}
+		attrs := make(map[string]slog.Value)
+		h.records[j].Attrs(func(attr slog.Attr) bool {
+			attrs[attr.Key] = attr.Value
+			return true
+		})
+		return h.records[j], attrs, true
+	}
+	return slog.Record{}, nil, false
+}
+
+func Test_slogLogging(t *testing.T) {
+	handler := &testLogHandler{}
+	storage := &testStorage{}
+	manager := newTestConsistencyManager(t, storage, &testDeleteSender{})
+	manager.SetSlogLogger(slog.New(handler))
+
+	ctx := context.Background()
+	if err := storage.SaveState(ctx, 7, []byte("{")); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	for _, tCase := range []struct {
+		name      string
+		action    func() error
+		msg       string
+		level     slog.Level
+		messageID int64
+		processor string
+	}{
+		{
+			name: "send node",
+			action: func() error {
+				return manager.SendNode(ctx, NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "start")
+			},
+			msg:       "send node",
+			level:     slog.LevelInfo,
+			messageID: 1,
+			processor: "start",
+		},
+		{
+			name:      "stale press",
+			action:    func() error { return manager.ProcessCallback(ctx, Actor{UserID: 1}, "", 1, 1, "start>0>5") },
+			msg:       "process callback",
+			level:     slog.LevelInfo,
+			messageID: 1,
+			processor: "start",
+		},
+		{
+			name:      "failed callback",
+			action:    func() error { return manager.ProcessCallback(ctx, Actor{UserID: 1}, "", 7, 1, "start>0>0") },
+			msg:       "process callback",
+			level:     slog.LevelError,
+			messageID: 7,
+			processor: "start",
+		},
+		{
+			name:      "chatter",
+			action:    func() error { return manager.ProcessMsg(ctx, Actor{UserID: 1}, 9, 1, "hello") },
+			msg:       "process message",
+			level:     slog.LevelDebug,
+			messageID: 9,
+		},
+	} {
+		err := tCase.action()
+		record, attrs, ok := handler.find(tCase.msg)
+		if !ok {
+			t.Fatal(tCase.name, "record not found")
+		}
+		if record.Level != tCase.level {
+			t.Error(tCase.name, "level non match:", record.Level)
+		}
+		if attrs[logKeyChatID].Int64() != 1 || attrs[logKeyMessageID].Int64() != tCase.messageID || attrs[logKeyProcessor].String() != tCase.processor {
+			t.Error(tCase.name, "attributes non match:", attrs)
+		}
+		if _, hasErr := attrs["error"]; hasErr != (err != nil) {
+			t.Error(tCase.name, "error attribute non match:", attrs)
+		}
+	}
+}
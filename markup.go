@@ -0,0 +1,310 @@
+package tgmanager
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlTextReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	htmlAttrReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+	htmlEntity       = regexp.MustCompile(`^&(#[0-9]+|#x[0-9a-fA-F]+|lt|gt|amp|quot);$`) // the only named entities telegram knows
+	htmlTags         = map[string]bool{
+		"b": true, "strong": true, "i": true, "em": true, "u": true, "ins": true,
+		"s": true, "strike": true, "del": true, "span": true, "tg-spoiler": true,
+		"a": true, "code": true, "pre": true, "blockquote": true, "tg-emoji": true,
+	}
+)
+
+const (
+	markdownV2Reserved = "_*[]()~`>#+-=|{}.!\\"
+	markdownV2Code     = "`\\"
+	markdownV2Link     = ")\\"
+)
+
+// EscapeText escapes s so that it is rendered literally in the given parse mode.
+func EscapeText(mode ParseMode, s string) string {
+	switch mode {
+	case ParseModeHtml:
+		return htmlTextReplacer.Replace(s)
+	case ParseModeMarkdownV2:
+		return escapeMarkdownV2(s, markdownV2Reserved)
+	}
+	return s
+}
+
+func escapeMarkdownV2(s, reserved string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for _, r := range s {
+		if strings.ContainsRune(reserved, r) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// TextBuilder composes a message in the given parse mode escaping every user provided part.
+// With an empty parse mode formatting is dropped and plain text is produced.
+type TextBuilder struct {
+	mode ParseMode
+	sb   strings.Builder
+}
+
+func NewTextBuilder(mode ParseMode) *TextBuilder {
+	return &TextBuilder{mode: mode}
+}
+
+func (t *TextBuilder) ParseMode() ParseMode {
+	return t.mode
+}
+
+func (t *TextBuilder) String() string {
+	return t.sb.String()
+}
+
+func (t *TextBuilder) Text(s string) *TextBuilder {
+	t.sb.WriteString(EscapeText(t.mode, s))
+	return t
+}
+
+func (t *TextBuilder) Bold(s string) *TextBuilder {
+	return t.wrap(s, "<b>", "</b>", "*", "*")
+}
+
+func (t *TextBuilder) Italic(s string) *TextBuilder {
+	return t.wrap(s, "<i>", "</i>", "_", "_")
+}
+
+func (t *TextBuilder) Spoiler(s string) *TextBuilder {
+	return t.wrap(s, "<tg-spoiler>", "</tg-spoiler>", "||", "||")
+}
+
+func (t *TextBuilder) Code(s string) *TextBuilder {
+	switch t.mode {
+	case ParseModeHtml:
+		t.sb.WriteString("<code>" + htmlTextReplacer.Replace(s) + "</code>")
+	case ParseModeMarkdownV2:
+		t.sb.WriteString("`" + escapeMarkdownV2(s, markdownV2Code) + "`")
+	default:
+		t.sb.WriteString(s)
+	}
+	return t
+}
+
+func (t *TextBuilder) Pre(s, language string) *TextBuilder {
+	switch t.mode {
+	case ParseModeHtml:
+		if language != "" {
+			t.sb.WriteString(fmt.Sprintf(`<pre><code class="language-%s">%s</code></pre>`,
+				htmlAttrReplacer.Replace(language), htmlTextReplacer.Replace(s)))
+		} else {
+			t.sb.WriteString("<pre>" + htmlTextReplacer.Replace(s) + "</pre>")
+		}
+	case ParseModeMarkdownV2:
+		t.sb.WriteString("```" + escapeMarkdownV2(language, markdownV2Code) + "\n" +
+			escapeMarkdownV2(s, markdownV2Code) + "\n```")
+	default:
+		t.sb.WriteString(s)
+	}
+	return t
+}
+
+func (t *TextBuilder) Link(label, url string) *TextBuilder {
+	switch t.mode {
+	case ParseModeHtml:
+		t.sb.WriteString(fmt.Sprintf(`<a href="%s">%s</a>`, htmlAttrReplacer.Replace(url), htmlTextReplacer.Replace(label)))
+	case ParseModeMarkdownV2:
+		t.sb.WriteString(fmt.Sprintf("[%s](%s)", escapeMarkdownV2(label, markdownV2Reserved), escapeMarkdownV2(url, markdownV2Link)))
+	default:
+		t.sb.WriteString(label)
+	}
+	return t
+}
+
+func (t *TextBuilder) Mention(label string, userID int64) *TextBuilder {
+	return t.Link(label, fmt.Sprintf("tg://user?id=%d", userID))
+}
+
+func (t *TextBuilder) wrap(s, htmlOpen, htmlClose, mdOpen, mdClose string) *TextBuilder {
+	switch t.mode {
+	case ParseModeHtml:
+		t.sb.WriteString(htmlOpen + htmlTextReplacer.Replace(s) + htmlClose)
+	case ParseModeMarkdownV2:
+		t.sb.WriteString(mdOpen + escapeMarkdownV2(s, markdownV2Reserved) + mdClose)
+	default:
+		t.sb.WriteString(s)
+	}
+	return t
+}
+
+func validateMarkup(text string, mode ParseMode) error {
+	return scanMarkup(text, mode, nil)
+}
+
+// scanMarkup validates text and reports through boundary every byte offset
+// at which no formatting entity is open, so the text can be cut there.
+func scanMarkup(text string, mode ParseMode, boundary func(i int)) error {
+	if boundary == nil {
+		boundary = func(int) {}
+	}
+	switch mode {
+	case "":
+		for i := 0; i <= len(text); i++ {
+			boundary(i)
+		}
+		return nil
+	case ParseModeHtml:
+		return scanHTML(text, boundary)
+	case ParseModeMarkdownV2:
+		return scanMarkdownV2(text, boundary)
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidParseMode, mode)
+}
+
+func scanHTML(text string, boundary func(i int)) error {
+	var stack []string
+	for i := 0; i < len(text); i++ {
+		if len(stack) == 0 {
+			boundary(i)
+		}
+		switch text[i] {
+		case '<':
+			end := strings.IndexByte(text[i:], '>')
+			if end < 0 {
+				return fmt.Errorf("%w: unterminated tag at %d", ErrInvalidMarkup, i)
+			}
+			tag := strings.TrimSpace(text[i+1 : i+end])
+			i += end
+			if strings.HasPrefix(tag, "/") {
+				name := strings.ToLower(strings.TrimSpace(tag[1:]))
+				if len(stack) == 0 || stack[len(stack)-1] != name {
+					return fmt.Errorf("%w: unexpected end tag %s", ErrInvalidMarkup, name)
+				}
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			name, _, _ := strings.Cut(tag, " ")
+			name = strings.ToLower(name)
+			if !htmlTags[name] {
+				return fmt.Errorf("%w: unsupported tag %s", ErrInvalidMarkup, name)
+			}
+			stack = append(stack, name)
+		case '>':
+			return fmt.Errorf("%w: unescaped '>' at %d", ErrInvalidMarkup, i)
+		case '&':
+			end := strings.IndexByte(text[i:], ';')
+			if end < 0 || !htmlEntity.MatchString(text[i:i+end+1]) {
+				return fmt.Errorf("%w: unescaped '&' at %d", ErrInvalidMarkup, i)
+			}
+			i += end
+		}
+	}
+	if len(stack) != 0 {
+		return fmt.Errorf("%w: unclosed tag %s", ErrInvalidMarkup, stack[len(stack)-1])
+	}
+	boundary(len(text))
+	return nil
+}
+
+func scanMarkdownV2(text string, boundary func(i int)) error {
+	var stack []string
+	toggle := func(marker string) error {
+		if len(stack) > 0 && stack[len(stack)-1] == marker {
+			stack = stack[:len(stack)-1]
+			return nil
+		}
+		for j := range stack {
+			if stack[j] == marker {
+				return fmt.Errorf("%w: overlapping %q entities", ErrInvalidMarkup, marker)
+			}
+		}
+		stack = append(stack, marker)
+		return nil
+	}
+
+	for i := 0; i < len(text); i++ {
+		if len(stack) == 0 {
+			boundary(i)
+		}
+		var err error
+		switch c := text[i]; c {
+		case '\\':
+			if i+1 >= len(text) {
+				return fmt.Errorf("%w: dangling escape", ErrInvalidMarkup)
+			}
+			i++
+		case '`':
+			marker := "`"
+			if strings.HasPrefix(text[i:], "```") {
+				marker = "```"
+			}
+			end := indexUnescaped(text, i+len(marker), marker)
+			if end < 0 {
+				return fmt.Errorf("%w: unclosed code entity", ErrInvalidMarkup)
+			}
+			i = end + len(marker) - 1
+		case '*', '~':
+			err = toggle(string(c))
+		case '_':
+			if strings.HasPrefix(text[i:], "__") {
+				err = toggle("__")
+				i++
+			} else {
+				err = toggle("_")
+			}
+		case '|':
+			if !strings.HasPrefix(text[i:], "||") {
+				return fmt.Errorf("%w: unescaped '|' at %d", ErrInvalidMarkup, i)
+			}
+			err = toggle("||")
+			i++
+		case '[':
+			stack = append(stack, "[")
+		case ']':
+			if len(stack) == 0 || stack[len(stack)-1] != "[" {
+				return fmt.Errorf("%w: unexpected ']' at %d", ErrInvalidMarkup, i)
+			}
+			stack = stack[:len(stack)-1]
+			if i+1 >= len(text) || text[i+1] != '(' {
+				return fmt.Errorf("%w: link without url at %d", ErrInvalidMarkup, i)
+			}
+			end := indexUnescaped(text, i+2, ")")
+			if end < 0 {
+				return fmt.Errorf("%w: unclosed link url at %d", ErrInvalidMarkup, i)
+			}
+			i = end
+		case '>':
+			if i != 0 && text[i-1] != '\n' {
+				return fmt.Errorf("%w: unescaped '>' at %d", ErrInvalidMarkup, i)
+			}
+		default:
+			if strings.IndexByte(markdownV2Reserved, c) >= 0 {
+				return fmt.Errorf("%w: unescaped '%c' at %d", ErrInvalidMarkup, c, i)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(stack) != 0 {
+		return fmt.Errorf("%w: unclosed %q entity", ErrInvalidMarkup, stack[len(stack)-1])
+	}
+	boundary(len(text))
+	return nil
+}
+
+func indexUnescaped(text string, from int, marker string) int {
+	for i := from; i < len(text); i++ {
+		if text[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(text[i:], marker) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,110 @@
+package tgmanager
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_TextBuilder(t *testing.T) {
+	for _, tCase := range []struct {
+		name     string
+		builder  *TextBuilder
+		expected string
+	}{
+		{
+			name:     "1",
+			builder:  NewTextBuilder(ParseModeHtml).Bold("a<b").Text(" & ").Link("x", `https://e.com/?a="1"`),
+			expected: `<b>a&lt;b</b> &amp; <a href="https://e.com/?a=&quot;1&quot;">x</a>`,
+		},
+		{
+			name:     "2",
+			builder:  NewTextBuilder(ParseModeMarkdownV2).Italic("1.5").Text(" ").Code("a`b").Spoiler("!"),
+			expected: "_1\\.5_ `a\\`b`||\\!||",
+		},
+		{
+			name:     "3",
+			builder:  NewTextBuilder(ParseModeMarkdownV2).Mention("bob_1", 42).Text(" ").Link("x", "https://e.com/(a)"),
+			expected: "[bob\\_1](tg://user?id=42) [x](https://e.com/(a\\))",
+		},
+		{
+			name:     "4",
+			builder:  NewTextBuilder("").Bold("a*b").Link("x", "https://e.com"),
+			expected: "a*bx",
+		},
+	} {
+		if actual := tCase.builder.String(); actual != tCase.expected {
+			t.Error(tCase.name, "actual:", actual, "expected:", tCase.expected)
+		}
+		if err := validateMarkup(tCase.builder.String(), tCase.builder.ParseMode()); err != nil {
+			t.Error(tCase.name, "built text is invalid:", err)
+		}
+	}
+}
+
+func Test_validateMarkup(t *testing.T) {
+	for _, tCase := range []struct {
+		name  string
+		text  string
+		mode  ParseMode
+		valid bool
+	}{
+		{name: "1", text: "a < b", mode: "", valid: true},
+		{name: "2", text: "<b>a</b> &lt; &#60;", mode: ParseModeHtml, valid: true},
+		{name: "3", text: "<b>a<i>b</b></i>", mode: ParseModeHtml, valid: false},
+		{name: "4", text: "a & b", mode: ParseModeHtml, valid: false},
+		{name: "5", text: "<script>x</script>", mode: ParseModeHtml, valid: false},
+		{name: "6", text: "<b>a", mode: ParseModeHtml, valid: false},
+		{name: "7", text: "*bold _it_*\n>quote", mode: ParseModeMarkdownV2, valid: true},
+		{name: "8", text: "price 1.5", mode: ParseModeMarkdownV2, valid: false},
+		{name: "9", text: "*bold", mode: ParseModeMarkdownV2, valid: false},
+		{name: "10", text: "[x](http://e.com", mode: ParseModeMarkdownV2, valid: false},
+		{name: "11", text: "```go\nfmt.Println(1.5)\n```", mode: ParseModeMarkdownV2, valid: true},
+		{name: "12", text: "x", mode: "Markdown", valid: false},
+		{name: "13", text: "&amp;&gt;&quot;&#x3c;", mode: ParseModeHtml, valid: true},
+		{name: "14", text: "a&nbsp;b", mode: ParseModeHtml, valid: false},
+		{name: "15", text: "&LT;", mode: ParseModeHtml, valid: false},
+	} {
+		err := validateMarkup(tCase.text, tCase.mode)
+		if (err == nil) != tCase.valid {
+			t.Error(tCase.name, "validity non match", "err:", err)
+		}
+	}
+}
+
+func Test_ProcessCallback_parseModeReset(t *testing.T) {
+	sender := &testChatSender{}
+	manager, err := NewCallbackManager("default", CallBackAppearTypeUpdate, nil, &testStorage{}, sender, nil, nil, nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	t.Cleanup(func() { _ = manager.Close(context.Background()) })
+	err = manager.AddProcessors(Processor{
+		Name: "start",
+		Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+			data.SetMsg("<b>Shop</b>")
+			data.SetParseMode(ParseModeHtml)
+			data.AddNode(NewDefaultNode("Plain", "plain", CallbackProcessorTypeProcess, nil))
+			return data, nil
+		},
+	}, Processor{
+		Name: "plain",
+		Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+			data.SetMsg("a < b & c")
+			return data, nil
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	ctx := context.Background()
+	if err = manager.SendNode(ctx, NewInOutData(1, 0, "", CallBackAppearTypeResend), "start"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err = manager.ProcessCallback(ctx, Actor{UserID: 1}, "", 1, 1, "plain>0>0"); err != nil {
+		t.Fatal("plain node must not inherit the parse mode, got", err)
+	}
+	if last := sender.containers[len(sender.containers)-1]; last.ParseMode != "" || last.Message != "a < b & c" {
+		t.Error("unexpected plain node:", last.ParseMode, last.Message)
+	}
+}
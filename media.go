@@ -0,0 +1,121 @@
+package tgmanager
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	maxCaptionLength = 1024
+	minAlbumSize     = 2
+	maxAlbumSize     = 10
+)
+
+// Media is a file attached to a message. Exactly one source must be set: a telegram file id,
+// an url or a reader. A reader is drained by the first send and is not persisted with the node
+// state, so reader media can't be re-sent on resend or retry, the processor has to open it again.
+// RetrySender and QueuedSender resend a reader only when it is an io.Seeker.
+// Prefer the file id telegram returns for an uploaded file to show it more than once.
+type Media struct {
+	Type     MediaType
+	FileID   string
+	URL      string
+	FileName string
+	Reader   io.Reader `json:"-"`
+}
+
+func NewMediaFileID(mediaType MediaType, fileID string) *Media {
+	return &Media{
+		Type:   mediaType,
+		FileID: fileID,
+	}
+}
+
+func NewMediaURL(mediaType MediaType, url string) *Media {
+	return &Media{
+		Type: mediaType,
+		URL:  url,
+	}
+}
+
+func NewMediaReader(mediaType MediaType, fileName string, reader io.Reader) *Media {
+	return &Media{
+		Type:     mediaType,
+		FileName: fileName,
+		Reader:   reader,
+	}
+}
+
+func (m *Media) validate() error {
+	if !m.Type.IsValid() {
+		return ErrInvalidMediaType
+	}
+
+	var sources int
+	for _, set := range []bool{m.FileID != "", m.URL != "", m.Reader != nil} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return errors.New("media requires exactly one source")
+	}
+	return nil
+}
+
+// validateAlbum checks telegram media group restrictions: 2-10 items, documents can't be mixed with other types.
+func validateAlbum(items []*Media) error {
+	if len(items) < minAlbumSize || len(items) > maxAlbumSize {
+		return fmt.Errorf("album must contain from %d to %d items", minAlbumSize, maxAlbumSize)
+	}
+
+	var documents int
+	for j := range items {
+		if items[j] == nil {
+			return errors.New("album item is nil")
+		}
+		if err := items[j].validate(); err != nil {
+			return fmt.Errorf("album item %d: %w", j, err)
+		}
+		if items[j].Type == MediaTypeDocument {
+			documents++
+		}
+	}
+	if documents != 0 && documents != len(items) {
+		return errors.New("album can't mix documents with other media")
+	}
+	return nil
+}
+
+// mediaRewinder returns a rewind of the media readers to their current offsets for a resend.
+// It returns nil when a reader can't be rewound since it is not an io.Seeker.
+func mediaRewinder(items ...*Media) func() error {
+	type offset struct {
+		seeker io.Seeker
+		at     int64
+	}
+	var offsets []offset
+	for _, item := range items {
+		if item == nil || item.Reader == nil {
+			continue
+		}
+		seeker, ok := item.Reader.(io.Seeker)
+		if !ok {
+			return nil
+		}
+		at, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil
+		}
+		offsets = append(offsets, offset{seeker: seeker, at: at})
+	}
+	return func() error {
+		for _, o := range offsets {
+			if _, err := o.seeker.Seek(o.at, io.SeekStart); err != nil {
+				return fmt.Errorf("rewinding media: %w", err)
+			}
+		}
+		return nil
+	}
+}
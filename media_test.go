@@ -0,0 +1,202 @@
+package tgmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testChatSender records everything sent. Update containers edit the old message,
+// other sends and every album item get a new id.
+type testChatSender struct {
+	testDeleteSender
+	lastID     int64
+	containers []TelegramContainer
+	albums     [][]*Media
+	threads    []int64
+	failChats  map[int64]bool
+	answers    []CallbackAnswer
+}
+
+func (s *testChatSender) SendMsg(_ context.Context, container TelegramContainer) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failChats[container.ChatID] {
+		return 0, errors.New("chat not found")
+	}
+	s.containers = append(s.containers, container)
+	if container.AppearType == CallBackAppearTypeUpdate && container.OldMessageID != 0 {
+		return container.OldMessageID, nil
+	}
+	s.lastID++
+	return s.lastID, nil
+}
+
+func (s *testChatSender) SendMediaGroup(_ context.Context, _, threadID int64, items []*Media) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]int64, 0, len(items))
+	for range items {
+		s.lastID++
+		ids = append(ids, s.lastID)
+	}
+	s.albums = append(s.albums, items)
+	s.threads = append(s.threads, threadID)
+	return ids, nil
+}
+
+func (s *testChatSender) AnswerCallback(_ context.Context, _ string, answer CallbackAnswer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.answers = append(s.answers, answer)
+	return nil
+}
+
+func Test_sendMedia(t *testing.T) {
+	for _, tCase := range []struct {
+		name       string
+		media      func() *Media
+		next       func() *Media
+		appearType CallBackAppearType
+	}{
+		{
+			name:       "file id to text",
+			media:      func() *Media { return NewMediaFileID(MediaTypePhoto, "file") },
+			next:       func() *Media { return nil },
+			appearType: CallBackAppearTypeResendDeleteOld,
+		},
+		{
+			name:       "url to url",
+			media:      func() *Media { return NewMediaURL(MediaTypeVideo, "https://example.com/a.mp4") },
+			next:       func() *Media { return NewMediaURL(MediaTypeVideo, "https://example.com/b.mp4") },
+			appearType: CallBackAppearTypeUpdate,
+		},
+		{
+			name:       "reader to text",
+			media:      func() *Media { return NewMediaReader(MediaTypeDocument, "a.txt", strings.NewReader("a")) },
+			next:       func() *Media { return nil },
+			appearType: CallBackAppearTypeResendDeleteOld,
+		},
+		{
+			name:       "text to reader",
+			media:      func() *Media { return nil },
+			next:       func() *Media { return NewMediaReader(MediaTypeDocument, "b.txt", strings.NewReader("b")) },
+			appearType: CallBackAppearTypeResendDeleteOld,
+		},
+		{
+			name:       "text to text",
+			media:      func() *Media { return nil },
+			next:       func() *Media { return nil },
+			appearType: CallBackAppearTypeUpdate,
+		},
+	} {
+		storage, sender := &testStorage{}, &testChatSender{}
+		manager, err := NewCallbackManager("default", CallBackAppearTypeUpdate, nil, storage, sender, nil, nil, nil)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		t.Cleanup(func() { _ = manager.Close(context.Background()) })
+		err = manager.AddProcessors(Processor{
+			Name: "start",
+			Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+				data.SetMedia(tCase.media())
+				data.AddNode(NewDefaultNode("next", "next", CallbackProcessorTypeProcess, nil))
+				return data, nil
+			},
+		}, Processor{
+			Name: "next",
+			Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+				data.SetMedia(tCase.next())
+				return data, nil
+			},
+		})
+		if err != nil {
+			t.Fatal(tCase.name, "unexpected error:", err)
+		}
+
+		ctx := context.Background()
+		if err = manager.SendNode(ctx, NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "start"); err != nil {
+			t.Fatal(tCase.name, "unexpected error:", err)
+		}
+		if media, expected := sender.containers[0].Media, tCase.media(); (media == nil) != (expected == nil) ||
+			(media != nil && (media.FileID != expected.FileID || media.URL != expected.URL || (media.Reader == nil) != (expected.Reader == nil))) {
+			t.Error(tCase.name, "media non match:", media)
+		}
+
+		if err = manager.ProcessCallback(ctx, Actor{UserID: 1}, "", 1, 1, "next>0>0"); err != nil {
+			t.Fatal(tCase.name, "unexpected error:", err)
+		}
+		if err = manager.Close(ctx); err != nil {
+			t.Fatal(tCase.name, "unexpected error:", err)
+		}
+		if appearType := sender.containers[1].AppearType; appearType != tCase.appearType {
+			t.Error(tCase.name, "appear type non match:", appearType)
+		}
+		if _, kept := storage.states[1]; kept == (tCase.appearType == CallBackAppearTypeResendDeleteOld) {
+			t.Error(tCase.name, "old state must be deleted only when the message is replaced")
+		}
+	}
+}
+
+func Test_sendAlbum(t *testing.T) {
+	for _, tCase := range []struct {
+		name    string
+		message string
+		parts   int
+	}{
+		{name: "short message", message: "album", parts: 1},
+		{name: "long message", message: strings.Repeat("a", maxMessageLength+1), parts: 2},
+	} {
+		storage, sender := &testStorage{}, &testChatSender{}
+		manager, err := NewCallbackManager("default", CallBackAppearTypeUpdate, nil, storage, sender, nil, nil, nil)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		t.Cleanup(func() { _ = manager.Close(context.Background()) })
+		err = manager.AddProcessors(Processor{
+			Name: "start",
+			Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+				data.SetMsg(tCase.message)
+				data.SetAlbum(NewMediaFileID(MediaTypePhoto, "a"), NewMediaFileID(MediaTypePhoto, "b"))
+				data.AddNode(NewDefaultNode("next", "start", CallbackProcessorTypeProcess, nil))
+				return data, nil
+			},
+		})
+		if err != nil {
+			t.Fatal(tCase.name, "unexpected error:", err)
+		}
+
+		ctx := WithActor(context.Background(), Actor{UserID: 1, ThreadID: 5})
+		if err = manager.SendNode(ctx, NewInOutData(1, 0, "", CallBackAppearTypeResend), "start"); err != nil {
+			t.Fatal(tCase.name, "unexpected error:", err)
+		}
+		if len(sender.albums) != 1 || len(sender.albums[0]) != 2 || sender.albums[0][0].FileID != "a" {
+			t.Fatal(tCase.name, "album must be sent once in order:", sender.albums)
+		}
+		if len(sender.containers) != tCase.parts {
+			t.Fatal(tCase.name, "unexpected number of messages:", len(sender.containers))
+		}
+		if sender.threads[0] != 5 {
+			t.Error(tCase.name, "album must be sent to the thread")
+		}
+		for j, container := range sender.containers {
+			if container.ThreadID != 5 {
+				t.Error(tCase.name, "message must be sent to the thread, message:", j)
+			}
+			if hasKeyboard := len(container.Buttons) != 0; hasKeyboard != (j == tCase.parts-1) {
+				t.Error(tCase.name, "keyboard must be on the last message only, message:", j)
+			}
+		}
+
+		// album ids go first, the keyboard message is the last one sent
+		var state inOutData
+		if err = json.Unmarshal(storage.states[sender.lastID], &state); err != nil {
+			t.Fatal(tCase.name, "unexpected error:", err)
+		}
+		if len(state.AlbumMessageIDs) != 2 || state.AlbumMessageIDs[1] >= state.MessageID || len(state.PartMessageIDs) != tCase.parts-1 {
+			t.Error(tCase.name, "unexpected linked messages:", state.AlbumMessageIDs, state.PartMessageIDs, state.MessageID)
+		}
+	}
+}
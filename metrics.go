@@ -0,0 +1,133 @@
+package tgmanager
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Metric names reported by the manager.
+const (
+	// MetricCallbacksTotal counts button presses by processor and callback_type
+	MetricCallbacksTotal = "tgmanager_callbacks_total"
+	// MetricMessagesTotal counts handled switch inline messages by processor
+	MetricMessagesTotal = "tgmanager_messages_total"
+	// MetricErrorsTotal counts failed operations by operation and class
+	MetricErrorsTotal = "tgmanager_errors_total"
+	// MetricProcessorSeconds is the processor latency by processor
+	MetricProcessorSeconds = "tgmanager_processor_duration_seconds"
+	// MetricStorageSeconds is the storage call latency by operation
+	MetricStorageSeconds = "tgmanager_storage_duration_seconds"
+	// MetricSenderSeconds is the telegram call latency by operation
+	MetricSenderSeconds = "tgmanager_sender_duration_seconds"
+	// MetricActiveStates is the number of states saved minus deleted since start
+	MetricActiveStates = "tgmanager_active_states"
+)
+
+const (
+	metricLabelProcessor    = "processor"
+	metricLabelCallbackType = "callback_type"
+	metricLabelOperation    = "operation"
+	metricLabelClass        = "class"
+
+	metricUnknownProcessor = "unknown"
+)
+
+type Labels map[string]string
+
+// Metrics receives the manager measurements, see PrometheusMetrics.
+type Metrics interface {
+	IncCounter(name string, labels Labels)
+	ObserveSeconds(name string, seconds float64, labels Labels)
+	AddGauge(name string, delta float64, labels Labels)
+}
+
+// SetMetrics enables metrics, nil disables them.
+func (c *callbackManager) SetMetrics(metrics Metrics) {
+	c.metrics = metrics
+}
+
+func (c *callbackManager) incCounter(name string, labels Labels) {
+	if c.metrics != nil {
+		c.metrics.IncCounter(name, labels)
+	}
+}
+
+func (c *callbackManager) observeSince(name string, start time.Time, labels Labels) {
+	if c.metrics != nil {
+		c.metrics.ObserveSeconds(name, time.Since(start).Seconds(), labels)
+	}
+}
+
+func (c *callbackManager) addGauge(name string, delta float64) {
+	if c.metrics != nil {
+		c.metrics.AddGauge(name, delta, nil)
+	}
+}
+
+// metricProcessor bounds the processor label, names coming from callbacks are untrusted.
+func (c *callbackManager) metricProcessor(name string) string {
+	if _, ok := c.allProcessors[name]; ok || name == "" {
+		return name
+	}
+	return metricUnknownProcessor
+}
+
+func (c *callbackManager) countError(op string, err error) {
+	if err != nil {
+		c.incCounter(MetricErrorsTotal, Labels{metricLabelOperation: op, metricLabelClass: errorClass(err)})
+	}
+}
+
+// errorClass groups errors into a small set of label values.
+func errorClass(err error) string {
+	switch {
+	case IsUserError(err):
+		return "user"
+	case errors.Is(err, ErrProcessorTimeout):
+		return "timeout"
+	case errors.Is(err, ErrProcessorPanic):
+		return "panic"
+	case errors.Is(err, ErrSendFailed):
+		return "send"
+	case errors.Is(err, ErrStorage):
+		return "storage"
+	case errors.Is(err, ErrStateCorrupt):
+		return "state"
+	case errors.Is(err, ErrInvalidData), errors.Is(err, ErrInvalidNode), errors.Is(err, ErrProcessorNotFound):
+		return "invalid"
+	default:
+		return "processor"
+	}
+}
+
+// instrumentedSender traces and measures the telegram calls.
+type instrumentedSender struct {
+	telegramSender
+	c *callbackManager
+}
+
+func (s *instrumentedSender) SendMsg(ctx context.Context, container TelegramContainer) (msgID int64, err error) {
+	ctx, done := s.c.instrument(ctx, MetricSenderSeconds, "sender", "send_message", Int64Attribute(AttrChatID, container.ChatID))
+	defer func() { done(err) }()
+	return s.telegramSender.SendMsg(ctx, container)
+}
+
+func (s *instrumentedSender) SendMediaGroup(ctx context.Context, chatID, threadID int64, items []*Media) (msgIDs []int64, err error) {
+	ctx, done := s.c.instrument(ctx, MetricSenderSeconds, "sender", "send_media_group", Int64Attribute(AttrChatID, chatID))
+	defer func() { done(err) }()
+	return s.telegramSender.SendMediaGroup(ctx, chatID, threadID, items)
+}
+
+func (s *instrumentedSender) DeleteMessage(messageID int64, chatID int64) {
+	_, done := s.c.instrument(context.Background(), MetricSenderSeconds, "sender", "delete_message",
+		Int64Attribute(AttrChatID, chatID), Int64Attribute(AttrMessageID, messageID))
+	defer done(nil)
+	s.telegramSender.DeleteMessage(messageID, chatID)
+}
+
+func (s *instrumentedSender) AnswerCallback(ctx context.Context, queryID string, answer CallbackAnswer) (err error) {
+	ctx, done := s.c.instrument(ctx, MetricSenderSeconds, "sender", "answer_callback")
+	defer func() { done(err) }()
+	return s.telegramSender.AnswerCallback(ctx, queryID, answer)
+}
@@ -0,0 +1,87 @@
+package tgmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// OutboundError is returned when some of the messages added with InOutData.AddMessages were not sent.
+// The main message is sent and saved by then.
+type OutboundError struct {
+	// Total is the number of messages tried, nil ones are skipped
+	Total  int
+	Failed []OutboundFailure
+}
+
+type OutboundFailure struct {
+	Index  int
+	ChatID int64
+	Err    error
+}
+
+func (e *OutboundError) Error() string {
+	return fmt.Sprintf("%d of %d outbound messages failed, first: chat %d: %s",
+		len(e.Failed), e.Total, e.Failed[0].ChatID, e.Failed[0].Err)
+}
+
+func (e *OutboundError) Unwrap() []error {
+	out := make([]error, 0, len(e.Failed))
+	for j := range e.Failed {
+		out = append(out, e.Failed[j].Err)
+	}
+	return out
+}
+
+// sendOutbound sends the additional messages of data in order. Each message keeps its own
+// appear type and state, a failed one doesn't stop the rest.
+func (c *callbackManager) sendOutbound(ctx context.Context, data InOutData) error {
+	messages := data.getMessages()
+	if len(messages) == 0 {
+		return nil
+	}
+
+	outErr := &OutboundError{}
+	for j, msg := range messages {
+		if msg == nil {
+			continue
+		}
+		outErr.Total++
+		msg.setDefaultMessage(c.defaultMsg)
+		msg.setDefaultAccess(NodeAccessShared)
+		if err := c.sendOutboundMessage(ctx, msg); err != nil {
+			outErr.Failed = append(outErr.Failed, OutboundFailure{
+				Index:  j,
+				ChatID: msg.GetChatID(),
+				Err:    err,
+			})
+		}
+	}
+	if len(outErr.Failed) != 0 {
+		return outErr
+	}
+	return nil
+}
+
+// sendOutboundMessage sends the message replacing the one it edits like a button press does:
+// the album and the leading parts of the stored state are removed with it.
+func (c *callbackManager) sendOutboundMessage(ctx context.Context, msg InOutData) error {
+	oldMsgID := msg.getMsgID()
+	var old replacedMessage
+	var stored bool
+	if msg.getAppearType() != CallBackAppearTypeResend && oldMsgID != 0 {
+		state, err := c.getDataFromStorage(ctx, oldMsgID)
+		if err != nil && !errors.Is(err, ErrStateNotFound) {
+			return err
+		}
+		old, stored = state.replaced(), state != nil
+	}
+
+	if err := c.send(ctx, msg, old); err != nil {
+		return err
+	}
+	if msg.getAppearType() == CallBackAppearTypeResendDeleteOld && oldMsgID != 0 {
+		c.deleteDataFromStorage(ctx, oldMsgID, stored)
+	}
+	return nil
+}
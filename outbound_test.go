@@ -0,0 +1,120 @@
+package tgmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func Test_sendOutbound(t *testing.T) {
+	storage, sender := &testStorage{}, &testChatSender{failChats: map[int64]bool{3: true}}
+	// the message edited by an outbound message has an album and a leading part
+	edited, _ := json.Marshal(&inOutData{ChatID: 4, MessageID: 100, AlbumMessageIDs: []int64{97, 98}, PartMessageIDs: []int64{99}})
+	storage.states = map[int64][]byte{100: edited}
+
+	manager, err := NewCallbackManager("default", CallBackAppearTypeUpdate, nil, storage, sender, nil, nil, nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	t.Cleanup(func() { _ = manager.Close(context.Background()) })
+	err = manager.AddProcessors(Processor{
+		Name: "start",
+		Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+			data.AddMessages(
+				NewInOutData(2, 0, "notify", CallBackAppearTypeResend),
+				nil,
+				NewInOutData(3, 0, "blocked", CallBackAppearTypeResend),
+				NewInOutData(4, 100, "edited", CallBackAppearTypeUpdate),
+			)
+			return data, nil
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err = manager.SendNode(context.Background(), NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "start")
+	var outErr *OutboundError
+	if !errors.As(err, &outErr) {
+		t.Fatal("expected outbound error, got", err)
+	}
+	if outErr.Total != 3 || len(outErr.Failed) != 1 || outErr.Failed[0].Index != 2 || outErr.Failed[0].ChatID != 3 {
+		t.Error("unexpected outbound error:", outErr.Total, outErr.Failed)
+	}
+	if err = manager.Close(context.Background()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for j, expected := range []struct {
+		chatID     int64
+		message    string
+		appearType CallBackAppearType
+	}{
+		{chatID: 1, message: "hello", appearType: CallBackAppearTypeResend},
+		{chatID: 2, message: "notify", appearType: CallBackAppearTypeResend},
+		{chatID: 4, message: "edited", appearType: CallBackAppearTypeUpdate},
+	} {
+		if j >= len(sender.containers) {
+			t.Fatal("missing message to chat", expected.chatID)
+		}
+		container := sender.containers[j]
+		if container.ChatID != expected.chatID || container.Message != expected.message || container.AppearType != expected.appearType {
+			t.Error("message non match:", container.ChatID, container.Message, container.AppearType)
+		}
+	}
+	if len(sender.deleted) != 3 {
+		t.Error("album and parts of the edited message must be deleted, deleted:", sender.deleted)
+	}
+	if _, ok := storage.states[100]; !ok {
+		t.Error("state of the edited message must be saved")
+	}
+}
+
+func Test_sendOutbound_access(t *testing.T) {
+	for _, tCase := range []struct {
+		name   string
+		access NodeAccess
+		err    error
+	}{
+		{name: "shared by default"},
+		{name: "owner node", access: NodeAccessOwner, err: ErrNotMenuOwner},
+	} {
+		sender := &testChatSender{}
+		manager, err := NewCallbackManager("default", CallBackAppearTypeUpdate, nil, &testStorage{}, sender, nil, nil, nil)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		t.Cleanup(func() { _ = manager.Close(context.Background()) })
+		manager.SetOwnerOnly(true, CallbackAnswer{Text: "Not your menu"})
+		err = manager.AddProcessors(Processor{
+			Name: "start",
+			Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+				notify := NewInOutData(2, 0, "notify", CallBackAppearTypeResend,
+					NewDefaultNode("Approve", "approve", CallbackProcessorTypeProcess, nil))
+				notify.SetAccess(tCase.access)
+				data.AddMessages(notify)
+				return data, nil
+			},
+		}, Processor{
+			Name: "approve",
+			Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+				data.SetMsg("approved")
+				return data, nil
+			},
+		})
+		if err != nil {
+			t.Fatal(tCase.name, "unexpected error:", err)
+		}
+
+		ctx := WithActor(context.Background(), Actor{UserID: 7, ChatType: ChatTypePrivate})
+		if err = manager.SendNode(ctx, NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "start"); err != nil {
+			t.Fatal(tCase.name, "unexpected error:", err)
+		}
+		// an admin presses the button of the notification in the admin chat
+		err = manager.ProcessCallback(context.Background(), Actor{UserID: 9, ChatType: ChatTypeGroup}, "", 2, 2, "approve>0>0")
+		if !errors.Is(err, tCase.err) {
+			t.Error(tCase.name, "error non match:", err)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package tgmanager
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the histogram upper bounds in seconds.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type metricKind string
+
+const (
+	metricKindCounter   metricKind = "counter"
+	metricKindGauge     metricKind = "gauge"
+	metricKindHistogram metricKind = "histogram"
+)
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+type metricFamily struct {
+	kind       metricKind
+	values     map[string]float64
+	histograms map[string]*histogram
+}
+
+// PrometheusMetrics keeps the metrics in memory and serves them in the prometheus text format.
+type PrometheusMetrics struct {
+	buckets []float64
+
+	mu       sync.Mutex
+	families map[string]*metricFamily
+}
+
+// NewPrometheusMetrics creates metrics with the histogram buckets, DefaultBuckets when none are given.
+func NewPrometheusMetrics(buckets ...float64) *PrometheusMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &PrometheusMetrics{
+		buckets:  buckets,
+		families: make(map[string]*metricFamily),
+	}
+}
+
+func (p *PrometheusMetrics) IncCounter(name string, labels Labels) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.family(name, metricKindCounter).values[formatLabels(labels)]++
+}
+
+func (p *PrometheusMetrics) AddGauge(name string, delta float64, labels Labels) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.family(name, metricKindGauge).values[formatLabels(labels)] += delta
+}
+
+func (p *PrometheusMetrics) ObserveSeconds(name string, seconds float64, labels Labels) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	family := p.family(name, metricKindHistogram)
+	key := formatLabels(labels)
+	h, ok := family.histograms[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(p.buckets))}
+		family.histograms[key] = h
+	}
+	for i, bound := range p.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+func (p *PrometheusMetrics) family(name string, kind metricKind) *metricFamily {
+	family, ok := p.families[name]
+	if !ok {
+		family = &metricFamily{
+			kind:       kind,
+			values:     make(map[string]float64),
+			histograms: make(map[string]*histogram),
+		}
+		p.families[name] = family
+	}
+	return family
+}
+
+// ServeHTTP writes all metrics in the prometheus text exposition format.
+func (p *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = p.write(w)
+}
+
+// write renders all metrics sorted by name and labels.
+func (p *PrometheusMetrics) write(w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var b strings.Builder
+	for _, name := range sortedKeys(p.families) {
+		family := p.families[name]
+		fmt.Fprintf(&b, "# TYPE %s %s\n", name, family.kind)
+		if family.kind != metricKindHistogram {
+			for _, labels := range sortedKeys(family.values) {
+				fmt.Fprintf(&b, "%s%s %s\n", name, braced(labels), formatFloat(family.values[labels]))
+			}
+			continue
+		}
+		for _, labels := range sortedKeys(family.histograms) {
+			h := family.histograms[labels]
+			for i, bound := range p.buckets {
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", name, braced(joinLabels(labels, `le="`+formatFloat(bound)+`"`)), h.counts[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", name, braced(joinLabels(labels, `le="+Inf"`)), h.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", name, braced(labels), formatFloat(h.sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", name, braced(labels), h.count)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// formatLabels renders sorted label pairs without braces, it is also the series key.
+func formatLabels(labels Labels) string {
+	pairs := make([]string, 0, len(labels))
+	for _, name := range sortedKeys(labels) {
+		pairs = append(pairs, name+`="`+escapeLabelValue(labels[name])+`"`)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func joinLabels(labels, extra string) string {
+	if labels == "" {
+		return extra
+	}
+	return labels + "," + extra
+}
+
+func braced(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
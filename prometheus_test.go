@@ -0,0 +1,114 @@
+package tgmanager
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_PrometheusMetrics(t *testing.T) {
+	metrics := NewPrometheusMetrics(0.5, 1)
+	metrics.IncCounter("requests_total", Labels{"path": `a"b`})
+	metrics.IncCounter("requests_total", Labels{"path": `a"b`})
+	metrics.AddGauge("active", 2, nil)
+	metrics.ObserveSeconds("latency_seconds", 0.7, Labels{"op": "get"})
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	expected := `# TYPE active gauge
+active 2
+# TYPE latency_seconds histogram
+latency_seconds_bucket{op="get",le="0.5"} 0
+latency_seconds_bucket{op="get",le="1"} 1
+latency_seconds_bucket{op="get",le="+Inf"} 1
+latency_seconds_sum{op="get"} 0.7
+latency_seconds_count{op="get"} 1
+# TYPE requests_total counter
+requests_total{path="a\"b"} 2
+`
+	if actual := recorder.Body.String(); actual != expected {
+		t.Error("exposition non match", "actual:\n", actual, "expected:\n", expected)
+	}
+}
+
+func Test_managerMetrics(t *testing.T) {
+	metrics := NewPrometheusMetrics()
+	manager := newTestConsistencyManager(t, &testStorage{}, &testDeleteSender{})
+	manager.SetMetrics(metrics)
+
+	ctx := context.Background()
+	if err := manager.SendNode(ctx, NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "start"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	_ = manager.ProcessCallback(ctx, Actor{}, "", 1, 1, "start>0>5")
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+	for _, line := range []string{
+		`tgmanager_active_states 1`,
+		`tgmanager_callbacks_total{callback_type="process",processor="start"} 1`,
+		`tgmanager_errors_total{class="user",operation="process_callback"} 1`,
+		`tgmanager_processor_duration_seconds_count{processor="start"} 1`,
+		`tgmanager_sender_duration_seconds_count{operation="send_message"} 1`,
+		`tgmanager_storage_duration_seconds_count{operation="save"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Error("missing metric line:", line)
+		}
+	}
+}
+
+func Test_managerMetrics_unknownProcessor(t *testing.T) {
+	metrics := NewPrometheusMetrics()
+	manager := newTestConsistencyManager(t, &testStorage{}, &testDeleteSender{})
+	manager.SetMetrics(metrics)
+
+	ctx := context.Background()
+	if err := manager.SendNode(ctx, NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "start"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	for _, value := range []string{"forged>0>0", "another forged>0>0"} {
+		_ = manager.ProcessCallback(ctx, Actor{}, "", 1, 1, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+	if !strings.Contains(body, `tgmanager_callbacks_total{callback_type="process",processor="unknown"} 2`+"\n") {
+		t.Error("callbacks of unregistered processors must share the unknown label")
+	}
+	if strings.Contains(body, "forged") {
+		t.Error("callback value must not become a label")
+	}
+}
+
+func Test_managerMetrics_activeStates(t *testing.T) {
+	metrics := NewPrometheusMetrics()
+	storage := &testStorage{}
+	manager := newTestConsistencyManager(t, storage, &testDeleteSender{})
+	manager.SetMetrics(metrics)
+
+	ctx := context.Background()
+	if err := manager.SendNode(ctx, NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "start"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	// a stale close button of a message whose state is gone, e.g. after a restart
+	if err := manager.ProcessCallback(ctx, Actor{}, "", 5, 1, ">2>0"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := manager.Close(ctx); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if body := recorder.Body.String(); !strings.Contains(body, "tgmanager_active_states 1\n") {
+		t.Error("only deleted existing states must be counted:\n", body)
+	}
+	if len(storage.states) != 1 {
+		t.Error("state of the sent message must be kept, states:", len(storage.states))
+	}
+}
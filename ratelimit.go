@@ -0,0 +1,278 @@
+package tgmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	memoryLimiterSweepEvery = 1024
+	defaultCoolDownInterval = time.Minute
+)
+
+// Rate allows Burst actions at once refilled by one every Interval. A zero rate is unlimited.
+type Rate struct {
+	Burst    int
+	Interval time.Duration
+}
+
+func (r Rate) isZero() bool {
+	return r.Burst <= 0 || r.Interval <= 0
+}
+
+type tokenBucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// take refills the bucket up to now and takes a token, it returns how long to wait when the bucket is empty.
+func (b *tokenBucket) take(rate Rate, now time.Time) (bool, time.Duration) {
+	if wait := b.wait(rate, now); wait > 0 {
+		return false, wait
+	}
+	b.Tokens--
+	return true, 0
+}
+
+// wait refills the bucket up to now and returns how long to wait for a token.
+func (b *tokenBucket) wait(rate Rate, now time.Time) time.Duration {
+	if b.Updated.IsZero() {
+		b.Tokens = float64(rate.Burst)
+	} else if elapsed := now.Sub(b.Updated); elapsed > 0 {
+		b.Tokens = min(float64(rate.Burst), b.Tokens+float64(elapsed)/float64(rate.Interval))
+	}
+	b.Updated = now
+
+	if b.Tokens < 1 {
+		return time.Duration((1 - b.Tokens) * float64(rate.Interval))
+	}
+	return 0
+}
+
+func (b *tokenBucket) isFull(rate Rate, now time.Time) bool {
+	return now.Sub(b.Updated) >= time.Duration(rate.Burst)*rate.Interval
+}
+
+// RateLimit is a bucket of an action stored under Key.
+type RateLimit struct {
+	Key  string
+	Rate Rate
+}
+
+// RateLimitBackend keeps token buckets, Allow takes a token from every bucket of the action
+// only when all of them have one, so a rejection doesn't burn the quota of the other buckets.
+type RateLimitBackend interface {
+	Allow(ctx context.Context, limits ...RateLimit) (bool, error)
+}
+
+// memoryBucket remembers its rate, idle buckets are swept with the rate they are refilled with.
+type memoryBucket struct {
+	tokenBucket
+	rate Rate
+}
+
+type memoryRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	calls   int
+}
+
+// NewMemoryRateLimiter keeps buckets in the process memory, idle buckets are dropped periodically.
+func NewMemoryRateLimiter() RateLimitBackend {
+	return &memoryRateLimiter{buckets: make(map[string]*memoryBucket)}
+}
+
+func (m *memoryRateLimiter) Allow(_ context.Context, limits ...RateLimit) (bool, error) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.calls++; m.calls%memoryLimiterSweepEvery == 0 {
+		for k, b := range m.buckets {
+			if b.isFull(b.rate, now) {
+				delete(m.buckets, k)
+			}
+		}
+	}
+
+	buckets := make([]*memoryBucket, 0, len(limits))
+	for _, limit := range limits {
+		b, ok := m.buckets[limit.Key]
+		if !ok {
+			b = &memoryBucket{}
+			m.buckets[limit.Key] = b
+		}
+		b.rate = limit.Rate
+		if b.wait(limit.Rate, now) > 0 {
+			return false, nil
+		}
+		buckets = append(buckets, b)
+	}
+	for _, b := range buckets {
+		b.take(b.rate, now)
+	}
+	return true, nil
+}
+
+// RateLimitStore persists buckets for limiters shared between bot instances.
+type RateLimitStore interface {
+	GetBucket(ctx context.Context, key string) (data []byte, err error)
+	SaveBucket(ctx context.Context, key string, data []byte, ttl time.Duration) (err error)
+}
+
+type storageRateLimiter struct {
+	store RateLimitStore
+}
+
+// NewStorageRateLimiter keeps buckets in the store. Read and write are not atomic,
+// concurrent actions of the same user may occasionally pass together.
+func NewStorageRateLimiter(store RateLimitStore) RateLimitBackend {
+	return &storageRateLimiter{store: store}
+}
+
+func (s *storageRateLimiter) Allow(ctx context.Context, limits ...RateLimit) (bool, error) {
+	now := time.Now()
+	buckets := make([]tokenBucket, len(limits))
+	for j, limit := range limits {
+		payload, err := s.store.GetBucket(ctx, limit.Key)
+		if err != nil {
+			return false, fmt.Errorf("getting bucket: %w", err)
+		}
+		if payload != nil {
+			if err = json.Unmarshal(payload, &buckets[j]); err != nil {
+				return false, fmt.Errorf("json unmarshal: %w", err)
+			}
+		}
+		if buckets[j].wait(limit.Rate, now) > 0 {
+			return false, nil
+		}
+	}
+
+	for j, limit := range limits {
+		buckets[j].take(limit.Rate, now)
+		payload, err := json.Marshal(buckets[j])
+		if err != nil {
+			return false, fmt.Errorf("json marshal: %w", err)
+		}
+		if err = s.store.SaveBucket(ctx, limit.Key, payload, time.Duration(limit.Rate.Burst)*limit.Rate.Interval); err != nil {
+			return false, fmt.Errorf("saving bucket: %w", err)
+		}
+	}
+	return true, nil
+}
+
+type RateLimitConfig struct {
+	UserRate Rate
+	ChatRate Rate
+	Backend  RateLimitBackend
+	Response ThrottleResponse
+	// Answer is shown for throttled callbacks with ThrottleResponseToast
+	Answer CallbackAnswer
+	// CoolDownMessage is sent with ThrottleResponseMessage, at most once per CoolDownInterval in a chat
+	CoolDownMessage string
+	// CoolDownInterval is a minute when zero
+	CoolDownInterval time.Duration
+}
+
+type ThrottleStats struct {
+	Callbacks uint64
+	Messages  uint64
+}
+
+type rateLimiter struct {
+	config    RateLimitConfig
+	coolDowns RateLimitBackend
+	callbacks atomic.Uint64
+	messages  atomic.Uint64
+}
+
+// SetRateLimit limits incoming callbacks and messages per user and per chat.
+func (c *callbackManager) SetRateLimit(config RateLimitConfig) {
+	if config.Backend == nil {
+		config.Backend = NewMemoryRateLimiter()
+	}
+	if config.CoolDownInterval <= 0 {
+		config.CoolDownInterval = defaultCoolDownInterval
+	}
+	c.rateLimiter = &rateLimiter{config: config, coolDowns: NewMemoryRateLimiter()}
+}
+
+// GetThrottleStats returns the number of actions rejected by the rate limit.
+func (c *callbackManager) GetThrottleStats() ThrottleStats {
+	if c.rateLimiter == nil {
+		return ThrottleStats{}
+	}
+	return ThrottleStats{
+		Callbacks: c.rateLimiter.callbacks.Load(),
+		Messages:  c.rateLimiter.messages.Load(),
+	}
+}
+
+// allowAction takes tokens of the user and the chat when both have one. Backend failures let the action through.
+func (c *callbackManager) allowAction(ctx context.Context, actor Actor, chatID int64) bool {
+	if c.rateLimiter == nil {
+		return true
+	}
+	config := c.rateLimiter.config
+
+	limits := make([]RateLimit, 0, 2)
+	if actor.UserID != 0 && !config.UserRate.isZero() {
+		limits = append(limits, RateLimit{Key: fmt.Sprintf("user:%d", actor.UserID), Rate: config.UserRate})
+	}
+	if !config.ChatRate.isZero() {
+		limits = append(limits, RateLimit{Key: fmt.Sprintf("chat:%d", chatID), Rate: config.ChatRate})
+	}
+	if len(limits) == 0 {
+		return true
+	}
+
+	allowed, err := config.Backend.Allow(ctx, limits...)
+	if err != nil {
+		c.logError(ctx, "rate limit", fmt.Errorf("rate limit: %w", err), slog.Int64(logKeyChatID, chatID))
+		return true
+	}
+	return allowed
+}
+
+// throttled responds to a rejected action, answer is nil for messages.
+func (c *callbackManager) throttled(ctx context.Context, chatID int64, answer func(CallbackAnswer)) error {
+	config := c.rateLimiter.config
+	if answer != nil {
+		c.rateLimiter.callbacks.Add(1)
+	} else {
+		c.rateLimiter.messages.Add(1)
+	}
+
+	switch config.Response {
+	case ThrottleResponseToast:
+		if answer != nil {
+			answer(config.Answer)
+		}
+	case ThrottleResponseMessage:
+		if answer != nil {
+			answer(CallbackAnswer{})
+		}
+		// the message itself must not turn spam into bot spam
+		coolDown := RateLimit{Key: fmt.Sprintf("cooldown:%d", chatID), Rate: Rate{Burst: 1, Interval: config.CoolDownInterval}}
+		if allowed, _ := c.rateLimiter.coolDowns.Allow(ctx, coolDown); !allowed {
+			break
+		}
+		actor, _ := ActorFromContext(ctx)
+		if _, err := c.sender.SendMsg(ctx, TelegramContainer{
+			ChatID:     chatID,
+			ThreadID:   actor.ThreadID,
+			Message:    config.CoolDownMessage,
+			AppearType: CallBackAppearTypeResend,
+		}); err != nil {
+			c.logError(ctx, "send cool down message", fmt.Errorf("sending cool down message: %w", err),
+				slog.Int64(logKeyChatID, chatID))
+		}
+	}
+	return ErrRateLimited
+}
@@ -0,0 +1,157 @@
+package tgmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func Test_tokenBucket(t *testing.T) {
+	rate := Rate{Burst: 2, Interval: time.Second}
+	now := time.Now()
+
+	var b tokenBucket
+	for _, tCase := range []struct {
+		name    string
+		at      time.Duration
+		allowed bool
+	}{
+		{name: "1", at: 0, allowed: true},
+		{name: "2", at: 0, allowed: true},
+		{name: "3", at: 500 * time.Millisecond, allowed: false},
+		{name: "4", at: 1100 * time.Millisecond, allowed: true},
+		{name: "5", at: 1200 * time.Millisecond, allowed: false},
+		{name: "6", at: 10 * time.Second, allowed: true},
+		{name: "7", at: 10 * time.Second, allowed: true},
+		{name: "8", at: 10 * time.Second, allowed: false},
+	} {
+		if allowed, _ := b.take(rate, now.Add(tCase.at)); allowed != tCase.allowed {
+			t.Error(tCase.name, "allowed non match", "actual:", allowed, "expected:", tCase.allowed)
+		}
+	}
+}
+
+func Test_memoryRateLimiter(t *testing.T) {
+	limiter := NewMemoryRateLimiter()
+	rate := Rate{Burst: 1, Interval: time.Hour}
+	user1, user2 := RateLimit{Key: "user:1", Rate: rate}, RateLimit{Key: "user:2", Rate: rate}
+	chat := RateLimit{Key: "chat:1", Rate: Rate{Burst: 2, Interval: time.Hour}}
+
+	for _, tCase := range []struct {
+		name    string
+		limits  []RateLimit
+		allowed bool
+	}{
+		{name: "1", limits: []RateLimit{user1}, allowed: true},
+		{name: "2", limits: []RateLimit{user1}, allowed: false},
+		{name: "3", limits: []RateLimit{user2, chat}, allowed: true},
+		{name: "4", limits: []RateLimit{chat}, allowed: true},
+		// the chat is empty, the user keeps the token
+		{name: "5", limits: []RateLimit{{Key: "user:3", Rate: rate}, chat}, allowed: false},
+		{name: "6", limits: []RateLimit{{Key: "user:3", Rate: rate}}, allowed: true},
+	} {
+		allowed, err := limiter.Allow(context.Background(), tCase.limits...)
+		if err != nil {
+			t.Error(tCase.name, "unexpected error:", err)
+		}
+		if allowed != tCase.allowed {
+			t.Error(tCase.name, "allowed non match", "actual:", allowed, "expected:", tCase.allowed)
+		}
+	}
+
+	// buckets of fast rates trigger sweeps, the empty chat bucket must survive them
+	for j := 0; j < memoryLimiterSweepEvery; j++ {
+		_, _ = limiter.Allow(context.Background(), RateLimit{Key: fmt.Sprint("fast:", j), Rate: Rate{Burst: 1, Interval: time.Nanosecond}})
+	}
+	if allowed, _ := limiter.Allow(context.Background(), chat); allowed {
+		t.Error("chat bucket must not be swept with the rate of other buckets")
+	}
+}
+
+func Test_throttled(t *testing.T) {
+	sender := &testChatSender{}
+	manager, err := NewCallbackManager("default", CallBackAppearTypeUpdate, nil, &testStorage{}, sender, nil, nil, nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	t.Cleanup(func() { _ = manager.Close(context.Background()) })
+	manager.SetRateLimit(RateLimitConfig{
+		UserRate:        Rate{Burst: 2, Interval: time.Hour},
+		ChatRate:        Rate{Burst: 1, Interval: time.Hour},
+		Response:        ThrottleResponseMessage,
+		CoolDownMessage: "slow down",
+	})
+
+	ctx := context.Background()
+	for _, tCase := range []struct {
+		name    string
+		userID  int64
+		chatID  int64
+		allowed bool
+	}{
+		{name: "1", userID: 1, chatID: 1, allowed: true},
+		{name: "2", userID: 1, chatID: 1},
+		{name: "3", userID: 1, chatID: 1},
+		// rejections by the chat limit don't burn the user quota
+		{name: "4", userID: 1, chatID: 2, allowed: true},
+	} {
+		err = manager.ProcessCallback(ctx, Actor{UserID: tCase.userID, ThreadID: 3}, "", 1, tCase.chatID, CallbackProcessorTypeIgnore.String())
+		if allowed := !errors.Is(err, ErrRateLimited); allowed != tCase.allowed {
+			t.Error(tCase.name, "allowed non match", "actual:", allowed, "expected:", tCase.allowed)
+		}
+	}
+	if len(sender.containers) != 1 || sender.containers[0].Message != "slow down" || sender.containers[0].ThreadID != 3 {
+		t.Error("cool down message must be sent once per interval, sent:", len(sender.containers))
+	}
+	if stats := manager.GetThrottleStats(); stats.Callbacks != 2 {
+		t.Error("unexpected throttle stats:", stats)
+	}
+}
+
+func Test_ProcessMsg_rateLimit(t *testing.T) {
+	sender := &testChatSender{}
+	manager, err := NewCallbackManager("default", CallBackAppearTypeUpdate, nil, &testStorage{}, sender, nil, nil, nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	t.Cleanup(func() { _ = manager.Close(context.Background()) })
+	err = manager.AddInlineProcessors(InlineProcessor{
+		Name: "search",
+		Processor: func(context.Context, string, string, int64, int64) (InOutData, string, error) {
+			return nil, "", nil
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	manager.SetRateLimit(RateLimitConfig{
+		ChatRate:        Rate{Burst: 2, Interval: time.Hour},
+		Response:        ThrottleResponseMessage,
+		CoolDownMessage: "slow down",
+	})
+
+	ctx := context.Background()
+	for _, tCase := range []struct {
+		name     string
+		message  string
+		expected error
+	}{
+		{name: "chatter 1", message: "hello", expected: ErrMessageProcessorNotFound},
+		{name: "chatter 2", message: "hello", expected: ErrMessageProcessorNotFound},
+		{name: "chatter 3", message: "hello", expected: ErrMessageProcessorNotFound},
+		{name: "unknown processor", message: "other" + inlineDivider + "query", expected: ErrMessageProcessorNotFound},
+		{name: "handled 1", message: "search" + inlineDivider + "query"},
+		{name: "handled 2", message: "search" + inlineDivider + "query"},
+		{name: "handled 3", message: "search" + inlineDivider + "query", expected: ErrRateLimited},
+		{name: "chatter past the limit", message: "hello", expected: ErrMessageProcessorNotFound},
+	} {
+		if err = manager.ProcessMsg(ctx, Actor{UserID: 1}, 1, -100, tCase.message); !errors.Is(err, tCase.expected) {
+			t.Error(tCase.name, "error non match", "actual:", err, "expected:", tCase.expected)
+		}
+	}
+	if len(sender.containers) != 1 {
+		t.Error("cool down message must be sent for the handled message only, sent:", len(sender.containers))
+	}
+}
@@ -0,0 +1,65 @@
+package tgmanager
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RenderContainer turns the container into a stable text for snapshot tests: the appear type,
+// thread, parse mode and media when set, the message and a row per button marked as callback,
+// link or inline.
+func RenderContainer(container TelegramContainer) string {
+	var b strings.Builder
+	if container.AppearType != "" {
+		fmt.Fprintf(&b, "appear: %s\n", container.AppearType)
+	}
+	if container.ThreadID != 0 {
+		fmt.Fprintf(&b, "thread: %d\n", container.ThreadID)
+	}
+	if container.ParseMode != "" {
+		fmt.Fprintf(&b, "parse mode: %s\n", container.ParseMode)
+	}
+	if media := container.Media; media != nil {
+		fmt.Fprintf(&b, "media: %s %s\n", media.Type, renderMediaSource(media))
+	}
+	b.WriteString(container.Message)
+	b.WriteString("\n")
+
+	if len(container.Buttons) == 0 {
+		return b.String()
+	}
+	b.WriteString("---\n")
+	for _, button := range container.Buttons {
+		switch {
+		case button.Callback != "":
+			var callback callbackParser
+			if err := callback.parseCallback(button.Callback); err != nil {
+				fmt.Fprintf(&b, "[callback] %s -> invalid %q\n", button.ButtonLabel, button.Callback)
+				continue
+			}
+			fmt.Fprintf(&b, "[callback] %s -> %s", button.ButtonLabel, callback.ProcessorType)
+			if callback.Processor != "" {
+				fmt.Fprintf(&b, " %s", callback.Processor)
+			}
+			b.WriteString("\n")
+		case button.Link != nil:
+			fmt.Fprintf(&b, "[link] %s -> %s\n", button.ButtonLabel, button.Link.GetLink())
+		case button.SwitchInlineQueryCurrentChat != nil:
+			fmt.Fprintf(&b, "[inline] %s -> %q\n", button.ButtonLabel, button.SwitchInlineQueryCurrentChat.GetText())
+		default:
+			fmt.Fprintf(&b, "[unknown] %s\n", button.ButtonLabel)
+		}
+	}
+	return b.String()
+}
+
+func renderMediaSource(media *Media) string {
+	switch {
+	case media.FileID != "":
+		return "file_id:" + media.FileID
+	case media.URL != "":
+		return "url:" + media.URL
+	default:
+		return "file:" + media.FileName
+	}
+}
@@ -0,0 +1,61 @@
+package tgmanager
+
+import (
+	"testing"
+)
+
+func Test_RenderContainer(t *testing.T) {
+	data := NewInOutData(1, 0, "<b>Shop</b>", CallBackAppearTypeResend,
+		NewDefaultNode("Buy", "buy", CallbackProcessorTypeProcess, nil),
+		NewLinkNode("Site", "https://example.com"),
+		NewInlineNode("Search", "search", ""),
+		NewDefaultNode("Back", "menu", CallbackProcessorTypeBack, nil),
+		NewDefaultNode("Close", "", CallbackProcessorTypeClose, nil),
+	)
+	data.SetParseMode(ParseModeHtml)
+	data.SetMedia(NewMediaFileID(MediaTypePhoto, "abc"))
+
+	container, err := data.generateTelegramContainer(nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := `appear: resend
+parse mode: HTML
+media: photo file_id:abc
+<b>Shop</b>
+---
+[callback] Buy -> process buy
+[link] Site -> https://example.com
+[inline] Search -> "search\n→ "
+[callback] Back -> back menu
+[callback] Close -> close
+`
+	if actual := RenderContainer(container); actual != expected {
+		t.Error("render non match", "actual:\n", actual, "expected:\n", expected)
+	}
+}
+
+func Test_RenderContainer_header(t *testing.T) {
+	for _, tCase := range []struct {
+		name      string
+		container TelegramContainer
+		expected  string
+	}{
+		{name: "empty", container: TelegramContainer{Message: "hi"}, expected: "hi\n"},
+		{
+			name:      "update in thread",
+			container: TelegramContainer{Message: "hi", AppearType: CallBackAppearTypeUpdate, ThreadID: 5},
+			expected:  "appear: update\nthread: 5\nhi\n",
+		},
+		{
+			name:      "resend delete old",
+			container: TelegramContainer{Message: "hi", AppearType: CallBackAppearTypeResendDeleteOld},
+			expected:  "appear: resend_delete_old\nhi\n",
+		},
+	} {
+		if actual := RenderContainer(tCase.container); actual != tCase.expected {
+			t.Error(tCase.name, "render non match", "actual:", actual, "expected:", tCase.expected)
+		}
+	}
+}
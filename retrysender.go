@@ -0,0 +1,282 @@
+package tgmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// retryAfterError may be implemented by sender errors for telegram "429 Too Many Requests" answers.
+type retryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+// SendError is a classified sender failure. Senders may return it directly,
+// otherwise ClassifySendError guesses the class from the telegram answer.
+type SendError struct {
+	Class      SendErrorClass
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *SendError) Error() string {
+	return fmt.Sprintf("%s send error: %s", e.Class, e.Err)
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// unreachableSendErrors are telegram answers for chats the bot can't write to anymore.
+var unreachableSendErrors = []string{
+	"bot was blocked by the user",
+	"bot was kicked",
+	"user is deactivated",
+	"chat not found",
+}
+
+// ClassifySendError returns the class of a sender error. Network failures are retryable, chats
+// which blocked or removed the bot are unreachable, unknown errors are considered permanent
+// to avoid repeating invalid requests.
+func ClassifySendError(err error) SendErrorClass {
+	if err == nil {
+		return ""
+	}
+
+	var sendErr *SendError
+	if errors.As(err, &sendErr) && sendErr.Class.IsValid() {
+		return sendErr.Class
+	}
+
+	var retryErr retryAfterError
+	if errors.As(err, &retryErr) && retryErr.RetryAfter() > 0 {
+		return SendErrorClassRateLimited
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return SendErrorClassRetryable
+	}
+
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "too many requests") {
+		return SendErrorClassRateLimited
+	}
+	for _, unreachable := range unreachableSendErrors {
+		if strings.Contains(msg, unreachable) {
+			return SendErrorClassUnreachable
+		}
+	}
+	if strings.Contains(msg, "internal server error") || strings.Contains(msg, "bad gateway") {
+		return SendErrorClassRetryable
+	}
+	return SendErrorClassPermanent
+}
+
+// toSendError wraps err into a SendError unless it is one already.
+func toSendError(err error) error {
+	var sendErr *SendError
+	if errors.As(err, &sendErr) {
+		return err
+	}
+	return &SendError{Class: ClassifySendError(err), RetryAfter: retryAfterOf(err), Err: err}
+}
+
+func retryAfterOf(err error) time.Duration {
+	var sendErr *SendError
+	if errors.As(err, &sendErr) {
+		return sendErr.RetryAfter
+	}
+	var retryErr retryAfterError
+	if errors.As(err, &retryErr) {
+		return retryErr.RetryAfter()
+	}
+	return 0
+}
+
+type RetryConfig struct {
+	// MaxAttempts includes the first try
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// BreakerThreshold is the number of consecutive retryable failures opening the circuit, zero disables it
+	BreakerThreshold int
+	// BreakerTimeout is how long the circuit stays open before a probe send
+	BreakerTimeout time.Duration
+	// OnUnreachable is called when the chat can't be written to, e.g. to clean state of users who blocked the bot
+	OnUnreachable func(ctx context.Context, chatID int64, err error)
+}
+
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxAttempts:      3,
+		BaseDelay:        200 * time.Millisecond,
+		MaxDelay:         5 * time.Second,
+		BreakerThreshold: 10,
+		BreakerTimeout:   30 * time.Second,
+	}
+}
+
+// RetrySender wraps a sender retrying retryable and rate limited sends with jittered
+// exponential backoff. During outages the circuit opens and sends fail with ErrCircuitOpen.
+// Media readers are rewound before a retry, sends with readers which are not io.Seeker are not retried.
+type RetrySender struct {
+	sender  telegramSender
+	config  RetryConfig
+	breaker circuitBreaker
+}
+
+func NewRetrySender(sender telegramSender, config RetryConfig) *RetrySender {
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	return &RetrySender{
+		sender: sender,
+		config: config,
+		breaker: circuitBreaker{
+			threshold: config.BreakerThreshold,
+			timeout:   config.BreakerTimeout,
+		},
+	}
+}
+
+func (r *RetrySender) SendMsg(ctx context.Context, container TelegramContainer) (msgID int64, err error) {
+	err = r.do(ctx, container.ChatID, mediaRewinder(container.Media), func() (err error) {
+		msgID, err = r.sender.SendMsg(ctx, container)
+		return err
+	})
+	return msgID, err
+}
+
+func (r *RetrySender) SendMediaGroup(ctx context.Context, chatID, threadID int64, items []*Media) (msgIDs []int64, err error) {
+	err = r.do(ctx, chatID, mediaRewinder(items...), func() (err error) {
+		msgIDs, err = r.sender.SendMediaGroup(ctx, chatID, threadID, items)
+		return err
+	})
+	return msgIDs, err
+}
+
+func (r *RetrySender) DeleteMessage(messageID int64, chatID int64) {
+	r.sender.DeleteMessage(messageID, chatID)
+}
+
+func (r *RetrySender) GetBotName() (string, error) {
+	return r.sender.GetBotName()
+}
+
+func (r *RetrySender) AnswerCallback(ctx context.Context, queryID string, answer CallbackAnswer) error {
+	return r.sender.AnswerCallback(ctx, queryID, answer)
+}
+
+// do sends until success or a non retryable failure, rewind prepares the next attempt and
+// a nil rewind allows only one.
+func (r *RetrySender) do(ctx context.Context, chatID int64, rewind func() error, send func() error) error {
+	maxAttempts := r.config.MaxAttempts
+	if rewind == nil {
+		maxAttempts = 1
+	}
+
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if !r.breaker.allow(time.Now()) {
+			return ErrCircuitOpen
+		}
+		if attempt > 0 {
+			if err = rewind(); err != nil {
+				return err
+			}
+		}
+
+		if err = send(); err == nil {
+			r.breaker.record(true, time.Now())
+			return nil
+		}
+
+		class := ClassifySendError(err)
+		r.breaker.record(class != SendErrorClassRetryable, time.Now())
+		if class == SendErrorClassUnreachable && r.config.OnUnreachable != nil {
+			r.config.OnUnreachable(ctx, chatID, err)
+		}
+		if class == SendErrorClassPermanent || class == SendErrorClassUnreachable {
+			return toSendError(err)
+		}
+		if attempt == maxAttempts-1 {
+			break
+		}
+
+		delay := r.backoff(attempt)
+		if class == SendErrorClassRateLimited {
+			delay = max(delay, retryAfterOf(err))
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return toSendError(err)
+}
+
+// backoff returns the exponential delay of the attempt with full jitter.
+func (r *RetrySender) backoff(attempt int) time.Duration {
+	if r.config.BaseDelay <= 0 {
+		return 0
+	}
+	delay := r.config.BaseDelay << attempt
+	if r.config.MaxDelay > 0 && (delay > r.config.MaxDelay || delay <= 0) {
+		delay = r.config.MaxDelay
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// circuitBreaker opens after threshold consecutive failures and lets a single probe through after timeout.
+type circuitBreaker struct {
+	threshold int
+	timeout   time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func (b *circuitBreaker) allow(now time.Time) bool {
+	if b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.probing || now.Sub(b.openedAt) < b.timeout {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *circuitBreaker) record(success bool, now time.Time) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		b.failures = 0
+		return
+	}
+	if b.failures++; b.failures >= b.threshold {
+		b.openedAt = now
+	}
+}
@@ -0,0 +1,116 @@
+package tgmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ClassifySendError(t *testing.T) {
+	for _, tCase := range []struct {
+		name     string
+		err      error
+		expected SendErrorClass
+	}{
+		{name: "1", err: errors.New("Forbidden: bot was blocked by the user"), expected: SendErrorClassUnreachable},
+		{name: "2", err: errors.New("Bad Request: chat not found"), expected: SendErrorClassUnreachable},
+		{name: "3", err: errors.New("Too Many Requests: retry after 5"), expected: SendErrorClassRateLimited},
+		{name: "4", err: testRetryAfterError{retryAfter: time.Second}, expected: SendErrorClassRateLimited},
+		{name: "5", err: fmt.Errorf("wrapped: %w", &SendError{Class: SendErrorClassRetryable}), expected: SendErrorClassRetryable},
+		{name: "6", err: errors.New("Internal Server Error"), expected: SendErrorClassRetryable},
+		{name: "7", err: errors.New("Bad Request: message is not modified"), expected: SendErrorClassPermanent},
+		{name: "8", err: errors.New("Forbidden: bot can't initiate conversation with a user"), expected: SendErrorClassPermanent},
+		{name: "9", err: nil, expected: ""},
+	} {
+		if actual := ClassifySendError(tCase.err); actual != tCase.expected {
+			t.Error(tCase.name, "class non match", "actual:", actual, "expected:", tCase.expected)
+		}
+	}
+}
+
+type testFlakySender struct {
+	testQueueSender
+	errs    []error
+	calls   int
+	payload string
+}
+
+func (s *testFlakySender) SendMsg(_ context.Context, container TelegramContainer) (int64, error) {
+	s.calls++
+	if container.Media != nil && container.Media.Reader != nil {
+		payload, err := io.ReadAll(container.Media.Reader)
+		if err != nil {
+			return 0, err
+		}
+		s.payload = string(payload)
+	}
+	if len(s.errs) == 0 {
+		return int64(s.calls), nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return 0, err
+}
+
+func Test_RetrySender(t *testing.T) {
+	blocked := errors.New("Forbidden: bot was blocked by the user")
+	outage := &SendError{Class: SendErrorClassRetryable, Err: errors.New("connection reset")}
+
+	var unreachableChat int64
+	sender := &testFlakySender{errs: []error{outage}}
+	retry := NewRetrySender(sender, RetryConfig{
+		MaxAttempts:      3,
+		BreakerThreshold: 2,
+		BreakerTimeout:   time.Hour,
+		OnUnreachable: func(_ context.Context, chatID int64, _ error) {
+			unreachableChat = chatID
+		},
+	})
+
+	if msgID, err := retry.SendMsg(context.Background(), TelegramContainer{ChatID: 1}); err != nil || msgID != 2 {
+		t.Error("expected success on the second attempt", "msg id:", msgID, "err:", err)
+	}
+
+	sender.errs = []error{blocked}
+	if _, err := retry.SendMsg(context.Background(), TelegramContainer{ChatID: 7}); ClassifySendError(err) != SendErrorClassUnreachable {
+		t.Error("expected unreachable error, actual:", err)
+	}
+	if sender.calls != 3 || unreachableChat != 7 {
+		t.Error("unreachable error must not be retried and must call the hook", "calls:", sender.calls, "chat:", unreachableChat)
+	}
+
+	sender.errs = []error{errors.New("Bad Request: message is not modified")}
+	if _, err := retry.SendMsg(context.Background(), TelegramContainer{ChatID: 8}); ClassifySendError(err) != SendErrorClassPermanent {
+		t.Error("expected permanent error, actual:", err)
+	}
+	if sender.calls != 4 || unreachableChat != 7 {
+		t.Error("permanent error must not be retried nor call the hook", "calls:", sender.calls, "chat:", unreachableChat)
+	}
+
+	sender.errs = []error{outage, outage, outage}
+	if _, err := retry.SendMsg(context.Background(), TelegramContainer{ChatID: 1}); !errors.Is(err, ErrCircuitOpen) {
+		t.Error("errors non match", "actual:", err, "expected:", ErrCircuitOpen)
+	}
+
+	// a drained reader can't be sent again, a seeker is rewound
+	retry = NewRetrySender(sender, RetryConfig{MaxAttempts: 3})
+	for _, tCase := range []struct {
+		name   string
+		reader io.Reader
+		calls  int
+	}{
+		{name: "reader", reader: io.MultiReader(strings.NewReader("file")), calls: 1},
+		{name: "seeker", reader: strings.NewReader("file"), calls: 2},
+	} {
+		sender.calls, sender.errs = 0, []error{outage}
+		media := NewMediaReader(MediaTypeDocument, "file.txt", tCase.reader)
+		_, err := retry.SendMsg(context.Background(), TelegramContainer{ChatID: 1, Media: media})
+		if sender.calls != tCase.calls || (err == nil) != (tCase.calls == 2) || sender.payload != "file" {
+			t.Error(tCase.name, "unexpected attempts:", sender.calls, "err:", err)
+		}
+	}
+}
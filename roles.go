@@ -0,0 +1,46 @@
+package tgmanager
+
+import (
+	"context"
+	"fmt"
+)
+
+// RoleResolverFunc returns roles of the user in the chat, they are matched against Processor.Roles.
+type RoleResolverFunc func(ctx context.Context, userID, chatID int64) (roles []string, err error)
+
+func (c *callbackManager) SetRoleResolver(resolver RoleResolverFunc) {
+	c.roleResolver = resolver
+}
+
+// processorFilter returns a check of processors available to the user in the chat. Without a role
+// resolver processors requiring roles are forbidden to everyone, an unknown user has no roles
+// either. A nil filter allows everything.
+func (c *callbackManager) processorFilter(ctx context.Context, userID, chatID int64) (func(processor string) bool, error) {
+	if len(c.processorRoles) == 0 {
+		return nil, nil
+	}
+
+	userRoles := make(map[string]struct{})
+	if c.roleResolver != nil && userID != 0 {
+		roles, err := c.roleResolver(ctx, userID, chatID)
+		if err != nil {
+			return nil, fmt.Errorf("resolving roles: %w", err)
+		}
+		for _, role := range roles {
+			userRoles[role] = struct{}{}
+		}
+	}
+
+	return func(processor string) bool {
+		required, ok := c.processorRoles[processor]
+		if !ok {
+			return true
+		}
+		for _, role := range required {
+			if _, ok = userRoles[role]; ok {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
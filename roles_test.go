@@ -0,0 +1,107 @@
+package tgmanager
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_generateTelegramContainer_roles(t *testing.T) {
+	data := NewInOutData(1, 0, "hello", CallBackAppearTypeResend,
+		NewDefaultNode("users", "users", CallbackProcessorTypeProcess, nil),
+		NewDefaultNode("ban", "ban", CallbackProcessorTypeProcess, nil),
+		NewDefaultNode("back", "admin", CallbackProcessorTypeBack, nil),
+	)
+	for _, tCase := range []struct {
+		name    string
+		allowed func(processor string) bool
+		labels  []string
+	}{
+		{name: "no filter", labels: []string{"users", "ban", "back"}},
+		{name: "filtered", allowed: func(processor string) bool { return processor == "users" }, labels: []string{"users"}},
+	} {
+		container, err := data.generateTelegramContainer(tCase.allowed)
+		if err != nil {
+			t.Fatal(tCase.name, "unexpected error:", err)
+		}
+		var labels []string
+		for _, button := range container.Buttons {
+			labels = append(labels, button.ButtonLabel)
+		}
+		if !reflect.DeepEqual(labels, tCase.labels) {
+			t.Error(tCase.name, "buttons non match:", labels)
+		}
+	}
+}
+
+func Test_processorRoles(t *testing.T) {
+	admin, user := Actor{UserID: 1}, Actor{UserID: 2}
+	errResolver := errors.New("resolver is down")
+	for _, tCase := range []struct {
+		name        string
+		opener      Actor
+		presser     Actor
+		resolverErr error
+		buttons     int
+		err         error
+	}{
+		{name: "allowed", opener: admin, presser: admin, buttons: 2},
+		{name: "forbidden callback", opener: user, presser: user, buttons: 1, err: ErrProcessorForbidden},
+		{name: "roles of the previous presser", opener: admin, presser: user, buttons: 2, err: ErrProcessorForbidden},
+		{name: "empty actor", opener: admin, presser: Actor{}, buttons: 2, err: ErrProcessorForbidden},
+		{name: "resolver error", opener: admin, presser: admin, buttons: 2, resolverErr: errResolver, err: errResolver},
+	} {
+		sender := &testChatSender{}
+		manager, err := NewCallbackManager("default", CallBackAppearTypeUpdate, nil, &testStorage{}, sender, nil, nil, nil)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		t.Cleanup(func() { _ = manager.Close(context.Background()) })
+		var resolverErr error
+		manager.SetRoleResolver(func(_ context.Context, userID, _ int64) ([]string, error) {
+			if userID == 0 {
+				t.Error(tCase.name, "roles must not be resolved for an unknown user")
+			}
+			if resolverErr != nil {
+				return nil, resolverErr
+			}
+			if userID == admin.UserID {
+				return []string{"admin"}, nil
+			}
+			return nil, nil
+		})
+		err = manager.AddProcessors(Processor{
+			Name: "start",
+			Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+				data.AddNode(NewDefaultNode("ban", "ban", CallbackProcessorTypeProcess, nil))
+				data.AddNode(NewDefaultNode("start", "start", CallbackProcessorTypeProcess, nil))
+				return data, nil
+			},
+		}, Processor{
+			Name:  "ban",
+			Roles: []string{"admin"},
+			Processor: func(_ context.Context, data InOutData) (InOutData, error) {
+				return data, nil
+			},
+		})
+		if err != nil {
+			t.Fatal(tCase.name, "unexpected error:", err)
+		}
+
+		ctx := context.Background()
+		if err = manager.SendNode(WithActor(ctx, tCase.opener), NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "start"); err != nil {
+			t.Fatal(tCase.name, "unexpected error:", err)
+		}
+		if buttons := len(sender.containers[0].Buttons); buttons != tCase.buttons {
+			t.Error(tCase.name, "unexpected number of buttons:", buttons)
+		}
+
+		// the ban button is pressed even when it is hidden, like a forged callback would
+		resolverErr = tCase.resolverErr
+		err = manager.ProcessCallback(ctx, tCase.presser, "query", 1, 1, "ban>0>0")
+		if !errors.Is(err, tCase.err) {
+			t.Error(tCase.name, "error non match:", err)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package tgmanager
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultSendRetries  = 3
+	sendQueueSweepChats = 1024
+)
+
+type SendQueueConfig struct {
+	// GlobalRate is the limit of the whole bot, telegram allows about 30 messages per second
+	GlobalRate Rate
+	// ChatRate is the limit of a single chat, telegram allows about one message per second
+	ChatRate Rate
+	// MaxRetries is the number of resends after a retry after answer
+	MaxRetries int
+}
+
+func DefaultSendQueueConfig() SendQueueConfig {
+	return SendQueueConfig{
+		GlobalRate: Rate{Burst: 30, Interval: time.Second / 30},
+		ChatRate:   Rate{Burst: 1, Interval: time.Second},
+		MaxRetries: defaultSendRetries,
+	}
+}
+
+type SendQueueStats struct {
+	Interactive int
+	Bulk        int
+	Retried     uint64
+}
+
+type sendResult struct {
+	msgIDs []int64
+	err    error
+}
+
+type sendJob struct {
+	ctx      context.Context
+	chatID   int64
+	priority SendPriority
+	attempts int
+	// rewind prepares a resend, nil when the job can't be resent
+	rewind func() error
+	run    func(ctx context.Context) ([]int64, error)
+	done   chan sendResult
+}
+
+type queueChat struct {
+	bucket       tokenBucket
+	blockedUntil time.Time
+}
+
+// QueuedSender wraps a sender queueing messages under the telegram limits. Interactive
+// sends go before bulk ones, see WithSendPriority. Deletes, callback answers and
+// the bot name requests are passed through.
+type QueuedSender struct {
+	sender  telegramSender
+	config  SendQueueConfig
+	retried atomic.Uint64
+
+	mu     sync.Mutex
+	queues [2][]*sendJob
+	global tokenBucket
+	chats  map[int64]*queueChat
+	closed bool
+
+	wake chan struct{}
+	stop chan struct{}
+}
+
+func NewQueuedSender(sender telegramSender, config SendQueueConfig) *QueuedSender {
+	q := &QueuedSender{
+		sender: sender,
+		config: config,
+		chats:  make(map[int64]*queueChat),
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+type sendPriorityCtxKey struct{}
+
+// WithSendPriority marks sends made with ctx, e.g. broadcasts as SendPriorityBulk.
+func WithSendPriority(ctx context.Context, priority SendPriority) context.Context {
+	return context.WithValue(ctx, sendPriorityCtxKey{}, priority)
+}
+
+func sendPriorityFromContext(ctx context.Context) SendPriority {
+	if priority, ok := ctx.Value(sendPriorityCtxKey{}).(SendPriority); ok && priority.IsValid() {
+		return priority
+	}
+	return SendPriorityInteractive
+}
+
+func (q *QueuedSender) SendMsg(ctx context.Context, container TelegramContainer) (int64, error) {
+	msgIDs, err := q.enqueue(ctx, container.ChatID, mediaRewinder(container.Media), func(ctx context.Context) ([]int64, error) {
+		msgID, err := q.sender.SendMsg(ctx, container)
+		return []int64{msgID}, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return msgIDs[0], nil
+}
+
+func (q *QueuedSender) SendMediaGroup(ctx context.Context, chatID, threadID int64, items []*Media) ([]int64, error) {
+	return q.enqueue(ctx, chatID, mediaRewinder(items...), func(ctx context.Context) ([]int64, error) {
+		return q.sender.SendMediaGroup(ctx, chatID, threadID, items)
+	})
+}
+
+func (q *QueuedSender) DeleteMessage(messageID int64, chatID int64) {
+	q.sender.DeleteMessage(messageID, chatID)
+}
+
+func (q *QueuedSender) GetBotName() (string, error) {
+	return q.sender.GetBotName()
+}
+
+func (q *QueuedSender) AnswerCallback(ctx context.Context, queryID string, answer CallbackAnswer) error {
+	return q.sender.AnswerCallback(ctx, queryID, answer)
+}
+
+func (q *QueuedSender) Stats() SendQueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return SendQueueStats{
+		Interactive: len(q.queues[SendPriorityInteractive]),
+		Bulk:        len(q.queues[SendPriorityBulk]),
+		Retried:     q.retried.Load(),
+	}
+}
+
+// Close stops the queue, pending sends fail with ErrSenderClosed.
+func (q *QueuedSender) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.closed = true
+	close(q.stop)
+	for p := range q.queues {
+		for _, job := range q.queues[p] {
+			job.done <- sendResult{err: ErrSenderClosed}
+		}
+		q.queues[p] = nil
+	}
+}
+
+func (q *QueuedSender) enqueue(ctx context.Context, chatID int64, rewind func() error, run func(ctx context.Context) ([]int64, error)) ([]int64, error) {
+	job := &sendJob{
+		ctx:      ctx,
+		chatID:   chatID,
+		priority: sendPriorityFromContext(ctx),
+		rewind:   rewind,
+		run:      run,
+		done:     make(chan sendResult, 1),
+	}
+
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil, ErrSenderClosed
+	}
+	q.queues[job.priority] = append(q.queues[job.priority], job)
+	q.mu.Unlock()
+	q.notify()
+
+	select {
+	case res := <-job.done:
+		return res.msgIDs, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (q *QueuedSender) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *QueuedSender) run() {
+	for {
+		job, wait := q.next(time.Now())
+		if job != nil {
+			go q.execute(job)
+			continue
+		}
+
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timeout = time.After(wait)
+		}
+		select {
+		case <-q.wake:
+		case <-timeout:
+		case <-q.stop:
+			return
+		}
+	}
+}
+
+// next pops the first job whose chat has a token, otherwise it returns how long to wait.
+// A zero wait means the queue is empty.
+func (q *QueuedSender) next(now time.Time) (*sendJob, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.chats) > sendQueueSweepChats {
+		q.sweepChats(now)
+	}
+
+	if !q.config.GlobalRate.isZero() {
+		if wait := q.global.wait(q.config.GlobalRate, now); wait > 0 {
+			return nil, wait
+		}
+	}
+
+	var wait time.Duration
+	for p := range q.queues {
+		for j := 0; j < len(q.queues[p]); j++ {
+			job := q.queues[p][j]
+			if job.ctx.Err() != nil {
+				q.queues[p] = append(q.queues[p][:j], q.queues[p][j+1:]...)
+				j--
+				continue
+			}
+
+			chat, ok := q.chats[job.chatID]
+			if !ok {
+				chat = &queueChat{}
+				q.chats[job.chatID] = chat
+			}
+			chatWait := chat.blockedUntil.Sub(now)
+			if chatWait <= 0 && !q.config.ChatRate.isZero() {
+				chatWait = chat.bucket.wait(q.config.ChatRate, now)
+			}
+			if chatWait > 0 {
+				if wait == 0 || chatWait < wait {
+					wait = chatWait
+				}
+				continue
+			}
+
+			if !q.config.ChatRate.isZero() {
+				chat.bucket.Tokens--
+			}
+			if !q.config.GlobalRate.isZero() {
+				q.global.Tokens--
+			}
+			q.queues[p] = append(q.queues[p][:j], q.queues[p][j+1:]...)
+			return job, 0
+		}
+	}
+	return nil, wait
+}
+
+func (q *QueuedSender) sweepChats(now time.Time) {
+	for chatID, chat := range q.chats {
+		if now.After(chat.blockedUntil) && chat.bucket.isFull(q.config.ChatRate, now) {
+			delete(q.chats, chatID)
+		}
+	}
+}
+
+// execute runs the job, a retry after answer (see SendError) blocks the chat and puts the job back to the queue head.
+// Jobs with media readers which can't be rewound are not resent.
+func (q *QueuedSender) execute(job *sendJob) {
+	msgIDs, err := job.run(job.ctx)
+
+	if retryAfter := retryAfterOf(err); retryAfter > 0 && job.rewind != nil && job.attempts < q.config.MaxRetries {
+		if rewindErr := job.rewind(); rewindErr != nil {
+			job.done <- sendResult{err: rewindErr}
+			return
+		}
+		job.attempts++
+		q.retried.Add(1)
+
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			job.done <- sendResult{err: ErrSenderClosed}
+			return
+		}
+		chat, ok := q.chats[job.chatID]
+		if !ok {
+			chat = &queueChat{}
+			q.chats[job.chatID] = chat
+		}
+		chat.blockedUntil = time.Now().Add(retryAfter)
+		q.queues[job.priority] = append([]*sendJob{job}, q.queues[job.priority]...)
+		q.mu.Unlock()
+		q.notify()
+		return
+	}
+	job.done <- sendResult{msgIDs: msgIDs, err: err}
+}
@@ -0,0 +1,108 @@
+package tgmanager
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testRetryAfterError struct {
+	retryAfter time.Duration
+}
+
+func (e testRetryAfterError) Error() string {
+	return "too many requests"
+}
+
+func (e testRetryAfterError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+type testQueueSender struct {
+	mu       sync.Mutex
+	messages []string
+	failures int
+}
+
+func (s *testQueueSender) SendMsg(_ context.Context, container TelegramContainer) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return 0, testRetryAfterError{retryAfter: 10 * time.Millisecond}
+	}
+	s.messages = append(s.messages, container.Message)
+	return int64(len(s.messages)), nil
+}
+
+func (s *testQueueSender) SendMediaGroup(context.Context, int64, int64, []*Media) ([]int64, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *testQueueSender) DeleteMessage(int64, int64) {}
+
+func (s *testQueueSender) GetBotName() (string, error) {
+	return "bot", nil
+}
+
+func (s *testQueueSender) AnswerCallback(context.Context, string, CallbackAnswer) error {
+	return nil
+}
+
+func Test_QueuedSender_retryAfter(t *testing.T) {
+	sender := &testQueueSender{failures: 2}
+	queue := NewQueuedSender(sender, SendQueueConfig{MaxRetries: defaultSendRetries})
+	defer queue.Close()
+
+	msgID, err := queue.SendMsg(context.Background(), TelegramContainer{ChatID: 1, Message: "hello"})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if msgID != 1 {
+		t.Error("msg id non match", "actual:", msgID, "expected:", 1)
+	}
+	if stats := queue.Stats(); stats.Retried != 2 {
+		t.Error("retried non match", "actual:", stats.Retried, "expected:", 2)
+	}
+
+	sender.failures = defaultSendRetries + 1
+	if _, err = queue.SendMsg(context.Background(), TelegramContainer{ChatID: 2, Message: "fail"}); err == nil {
+		t.Error("expected error after retries are exhausted")
+	}
+
+	// a drained reader can't be resent
+	sender.failures = 1
+	retried := queue.Stats().Retried
+	media := NewMediaReader(MediaTypeDocument, "file.txt", io.MultiReader(strings.NewReader("file")))
+	if _, err = queue.SendMsg(context.Background(), TelegramContainer{ChatID: 3, Media: media}); err == nil {
+		t.Error("expected error for reader media")
+	}
+	if stats := queue.Stats(); stats.Retried != retried {
+		t.Error("reader media must not be retried")
+	}
+}
+
+func Test_QueuedSender_chatRate(t *testing.T) {
+	sender := &testQueueSender{}
+	queue := NewQueuedSender(sender, SendQueueConfig{ChatRate: Rate{Burst: 1, Interval: 50 * time.Millisecond}})
+	defer queue.Close()
+
+	start := time.Now()
+	for _, msg := range []string{"1", "2", "3"} {
+		if _, err := queue.SendMsg(context.Background(), TelegramContainer{ChatID: 1, Message: msg}); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Error("chat rate is not honored, elapsed:", elapsed)
+	}
+
+	queue.Close()
+	if _, err := queue.SendMsg(context.Background(), TelegramContainer{ChatID: 1}); !errors.Is(err, ErrSenderClosed) {
+		t.Error("errors non match", "actual:", err, "expected:", ErrSenderClosed)
+	}
+}
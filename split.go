@@ -0,0 +1,125 @@
+package tgmanager
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxMessageLength = 4096
+
+var splitSeparators = []string{"\n\n", "\n", " "}
+
+// textLength counts text the way Telegram does, in UTF-16 code units.
+func textLength(text string) int {
+	var out int
+	for _, r := range text {
+		if r >= 0x10000 {
+			out += 2
+		} else {
+			out++
+		}
+	}
+	return out
+}
+
+// prefixEnd returns the byte offset of the longest prefix of text not exceeding limit.
+func prefixEnd(text string, limit int) int {
+	var length int
+	for i, r := range text {
+		if r >= 0x10000 {
+			length += 2
+		} else {
+			length++
+		}
+		if length > limit {
+			return i
+		}
+	}
+	return len(text)
+}
+
+// splitMessage cuts text into parts not longer than limit preferring paragraph, line and word
+// boundaries. Cuts are never made inside a formatting entity.
+func splitMessage(text string, mode ParseMode, limit int) ([]string, error) {
+	if textLength(text) <= limit {
+		return []string{text}, nil
+	}
+
+	safe := make([]bool, len(text)+1)
+	if err := scanMarkup(text, mode, func(i int) { safe[i] = true }); err != nil {
+		return nil, err
+	}
+
+	var parts []string
+	start := 0
+	for textLength(text[start:]) > limit {
+		end := start + prefixEnd(text[start:], limit)
+		cut, next := lastSafeSeparator(text, safe, start, end)
+		if cut < 0 {
+			for p := end; p > start; p-- {
+				if safe[p] && utf8.RuneStart(text[p]) {
+					cut, next = p, p
+					break
+				}
+			}
+		}
+		if cut <= start {
+			return nil, fmt.Errorf("%w: entity longer than %d characters", ErrInvalidMarkup, limit)
+		}
+		parts = append(parts, text[start:cut])
+		start = next
+	}
+	return append(parts, text[start:]), nil
+}
+
+func lastSafeSeparator(text string, safe []bool, start, end int) (cut, next int) {
+	for _, sep := range splitSeparators {
+		for to := min(end+len(sep), len(text)); to > start; {
+			idx := strings.LastIndex(text[start:to], sep)
+			if idx <= 0 {
+				break
+			}
+			if pos := start + idx; safe[pos] && safe[pos+len(sep)] {
+				return pos, pos + len(sep)
+			}
+			to = start + idx
+		}
+	}
+	return -1, -1
+}
+
+// splitTelegramContainer turns a container into the messages to send in order. The keyboard
+// always belongs to the last one. A media whose caption doesn't fit is sent without caption
+// followed by the text.
+func splitTelegramContainer(cont TelegramContainer) ([]TelegramContainer, error) {
+	var out []TelegramContainer
+	if cont.Media != nil {
+		if textLength(cont.Message) <= maxCaptionLength {
+			return []TelegramContainer{cont}, nil
+		}
+		out = append(out, TelegramContainer{
+			ChatID:     cont.ChatID,
+			ThreadID:   cont.ThreadID,
+			Media:      cont.Media,
+			AppearType: CallBackAppearTypeResend,
+		})
+		cont.Media = nil
+	}
+
+	parts, err := splitMessage(cont.Message, cont.ParseMode, maxMessageLength)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts[:len(parts)-1] {
+		out = append(out, TelegramContainer{
+			ChatID:     cont.ChatID,
+			ThreadID:   cont.ThreadID,
+			Message:    part,
+			ParseMode:  cont.ParseMode,
+			AppearType: CallBackAppearTypeResend,
+		})
+	}
+	cont.Message = parts[len(parts)-1]
+	return append(out, cont), nil
+}
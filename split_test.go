@@ -0,0 +1,118 @@
+package tgmanager
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_splitMessage(t *testing.T) {
+	for _, tCase := range []struct {
+		name     string
+		text     string
+		mode     ParseMode
+		limit    int
+		expected []string
+		err      bool
+	}{
+		{
+			name:     "1",
+			text:     "short",
+			limit:    10,
+			expected: []string{"short"},
+		},
+		{
+			name:     "2",
+			text:     "first line\n\nsecond\nthird",
+			limit:    15,
+			expected: []string{"first line", "second\nthird"},
+		},
+		{
+			name:     "3",
+			text:     "aaaa bbbb cccc",
+			limit:    9,
+			expected: []string{"aaaa bbbb", "cccc"},
+		},
+		{
+			name:     "4",
+			text:     "<b>aa bb</b> cc dd",
+			mode:     ParseModeHtml,
+			limit:    14,
+			expected: []string{"<b>aa bb</b>", "cc dd"},
+		},
+		{
+			name:     "5",
+			text:     "*aaaa bbbb cccc*",
+			mode:     ParseModeMarkdownV2,
+			limit:    10,
+			expected: nil,
+			err:      true,
+		},
+		{
+			name:     "6",
+			text:     strings.Repeat("я", 5),
+			limit:    2,
+			expected: []string{"яя", "яя", "я"},
+		},
+	} {
+		parts, err := splitMessage(tCase.text, tCase.mode, tCase.limit)
+		if (err != nil) != tCase.err {
+			t.Error(tCase.name, "errors non match", "err:", err)
+		}
+		if strings.Join(parts, "|") != strings.Join(tCase.expected, "|") {
+			t.Error(tCase.name, "parts non match", "actual:", parts, "expected:", tCase.expected)
+		}
+	}
+}
+
+func Test_splitTelegramContainer(t *testing.T) {
+	photo := NewMediaFileID(MediaTypePhoto, "file")
+	for _, tCase := range []struct {
+		name      string
+		container TelegramContainer
+		expected  int
+		media     []bool
+	}{
+		{
+			name:      "1",
+			container: TelegramContainer{Message: "caption", Media: photo, AppearType: CallBackAppearTypeUpdate},
+			expected:  1,
+			media:     []bool{true},
+		},
+		{
+			name:      "2",
+			container: TelegramContainer{Message: strings.Repeat("a", maxCaptionLength+1), Media: photo, ThreadID: 5},
+			expected:  2,
+			media:     []bool{true, false},
+		},
+		{
+			name:      "3",
+			container: TelegramContainer{Message: strings.Repeat("a ", maxMessageLength), ThreadID: 5},
+			expected:  2,
+			media:     []bool{false, false},
+		},
+	} {
+		containers, err := splitTelegramContainer(tCase.container)
+		if err != nil {
+			t.Error(tCase.name, "unexpected error:", err)
+			continue
+		}
+		if len(containers) != tCase.expected {
+			t.Error(tCase.name, "count non match", "actual:", len(containers), "expected:", tCase.expected)
+			continue
+		}
+		for j := range containers {
+			if (containers[j].Media != nil) != tCase.media[j] {
+				t.Error(tCase.name, "media non match at", j)
+			}
+			if containers[j].ThreadID != tCase.container.ThreadID {
+				t.Error(tCase.name, "thread non match at", j)
+			}
+			if j < len(containers)-1 && containers[j].AppearType != CallBackAppearTypeResend {
+				t.Error(tCase.name, "leading part must be resent at", j)
+			}
+		}
+		if containers[len(containers)-1].AppearType != tCase.container.AppearType {
+			t.Error(tCase.name, "last part appear type changed")
+		}
+	}
+}
@@ -2,8 +2,11 @@ package tgmanager
 
 type TelegramContainer struct {
 	ChatID       int64
+	ThreadID     int64
 	OldMessageID int64
 	Message      string
+	ParseMode    ParseMode
+	Media        *Media
 	AppearType   CallBackAppearType
 	Buttons      []Button
 }
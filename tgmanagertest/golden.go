@@ -0,0 +1,47 @@
+package tgmanagertest
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tarmalonchik/tgmanager"
+)
+
+// UpdateGoldenEnv rewrites golden files with the actual output when set to 1.
+const UpdateGoldenEnv = "TGMANAGER_UPDATE_GOLDEN"
+
+// AssertGolden compares actual with the file testdata/<name>.golden. A missing file fails the test,
+// files are written with the actual output only when UpdateGoldenEnv is set.
+func AssertGolden(t testing.TB, name, actual string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if os.Getenv(UpdateGoldenEnv) == "1" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal("creating golden dir:", err)
+		}
+		if err := os.WriteFile(path, []byte(actual), 0o644); err != nil {
+			t.Fatal("writing golden file:", err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("snapshot %s is missing, set %s=1 to create it", name, UpdateGoldenEnv)
+	}
+	if err != nil {
+		t.Fatal("reading golden file:", err)
+	}
+	if string(expected) != actual {
+		t.Errorf("snapshot %s non match, set %s=1 to update\nactual:\n%s\nexpected:\n%s", name, UpdateGoldenEnv, actual, expected)
+	}
+}
+
+// AssertSnapshot compares the rendered current message with the golden file of the name.
+func (u *User) AssertSnapshot(name string) {
+	u.t.Helper()
+	AssertGolden(u.t, name, tgmanager.RenderContainer(u.Current()))
+}
@@ -0,0 +1,183 @@
+// Package tgmanagertest provides fakes and a scripted user for testing tgmanager flows.
+package tgmanagertest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/tarmalonchik/tgmanager"
+)
+
+const defaultBotName = "testbot"
+
+// Message is a message shown in the fake chat.
+type Message struct {
+	ID        int64
+	Container tgmanager.TelegramContainer
+	Media     []*tgmanager.Media
+	Deleted   bool
+	// FromUser marks messages typed by the user, their container has the chat id and the text only
+	FromUser bool
+}
+
+// Sender is a recording telegram sender. Update containers edit the old message,
+// others are sent as new messages, resend_delete_old ones delete the old message.
+type Sender struct {
+	// BotName is returned by GetBotName, "testbot" when empty
+	BotName string
+	// Err fails all sends when set
+	Err error
+
+	mu       sync.Mutex
+	lastID   int64
+	messages map[int64]map[int64]*Message
+	sent     []tgmanager.TelegramContainer
+	answers  []tgmanager.CallbackAnswer
+}
+
+func NewSender() *Sender {
+	return &Sender{messages: make(map[int64]map[int64]*Message)}
+}
+
+func (s *Sender) SendMsg(_ context.Context, container tgmanager.TelegramContainer) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return 0, s.Err
+	}
+	s.sent = append(s.sent, container)
+
+	chat := s.chat(container.ChatID)
+	if container.AppearType == tgmanager.CallBackAppearTypeUpdate {
+		if msg, ok := chat[container.OldMessageID]; ok && !msg.Deleted {
+			msg.Container = container
+			return msg.ID, nil
+		}
+	}
+	if container.AppearType == tgmanager.CallBackAppearTypeResendDeleteOld {
+		if msg, ok := chat[container.OldMessageID]; ok {
+			msg.Deleted = true
+		}
+	}
+	s.lastID++
+	chat[s.lastID] = &Message{ID: s.lastID, Container: container}
+	return s.lastID, nil
+}
+
+func (s *Sender) SendMediaGroup(_ context.Context, chatID, threadID int64, items []*tgmanager.Media) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	if len(items) == 0 {
+		return nil, errors.New("empty media group")
+	}
+
+	chat := s.chat(chatID)
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		s.lastID++
+		chat[s.lastID] = &Message{ID: s.lastID, Container: tgmanager.TelegramContainer{ChatID: chatID, ThreadID: threadID}, Media: []*tgmanager.Media{item}}
+		ids = append(ids, s.lastID)
+	}
+	return ids, nil
+}
+
+func (s *Sender) DeleteMessage(messageID int64, chatID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if msg, ok := s.chat(chatID)[messageID]; ok {
+		msg.Deleted = true
+	}
+}
+
+func (s *Sender) GetBotName() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.BotName == "" {
+		return defaultBotName, nil
+	}
+	return s.BotName, nil
+}
+
+func (s *Sender) AnswerCallback(_ context.Context, _ string, answer tgmanager.CallbackAnswer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.answers = append(s.answers, answer)
+	return nil
+}
+
+// receive records the message typed by the user, it shares the ids with the bot messages
+// like telegram does.
+func (s *Sender) receive(chatID int64, text string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastID++
+	s.chat(chatID)[s.lastID] = &Message{
+		ID:        s.lastID,
+		Container: tgmanager.TelegramContainer{ChatID: chatID, Message: text},
+		FromUser:  true,
+	}
+	return s.lastID
+}
+
+// Messages returns the messages of the chat which are not deleted ordered by id.
+func (s *Sender) Messages(chatID int64) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []Message
+	for _, msg := range s.chat(chatID) {
+		if !msg.Deleted {
+			out = append(out, *msg)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Last returns the latest message of the chat.
+func (s *Sender) Last(chatID int64) (Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var last *Message
+	for _, msg := range s.chat(chatID) {
+		if last == nil || msg.ID > last.ID {
+			last = msg
+		}
+	}
+	if last == nil {
+		return Message{}, false
+	}
+	return *last, true
+}
+
+// Sent returns all sent and edited containers in order.
+func (s *Sender) Sent() []tgmanager.TelegramContainer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]tgmanager.TelegramContainer(nil), s.sent...)
+}
+
+// Answers returns all callback answers in order.
+func (s *Sender) Answers() []tgmanager.CallbackAnswer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]tgmanager.CallbackAnswer(nil), s.answers...)
+}
+
+func (s *Sender) chat(chatID int64) map[int64]*Message {
+	if s.messages == nil {
+		s.messages = make(map[int64]map[int64]*Message)
+	}
+	chat, ok := s.messages[chatID]
+	if !ok {
+		chat = make(map[int64]*Message)
+		s.messages[chatID] = chat
+	}
+	return chat
+}
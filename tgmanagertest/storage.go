@@ -0,0 +1,58 @@
+package tgmanagertest
+
+import (
+	"context"
+	"sync"
+)
+
+// Storage keeps states in memory.
+type Storage struct {
+	// Err fails all calls when set
+	Err error
+
+	mu     sync.Mutex
+	states map[int64][]byte
+}
+
+func NewStorage() *Storage {
+	return &Storage{states: make(map[int64][]byte)}
+}
+
+func (s *Storage) SaveState(_ context.Context, key int64, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	if s.states == nil {
+		s.states = make(map[int64][]byte)
+	}
+	s.states[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *Storage) GetState(_ context.Context, key int64) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	return s.states[key], nil
+}
+
+func (s *Storage) DeleteState(_ context.Context, key int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	delete(s.states, key)
+	return nil
+}
+
+// Len returns the number of stored states.
+func (s *Storage) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.states)
+}
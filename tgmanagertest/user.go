@@ -0,0 +1,155 @@
+package tgmanagertest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/tarmalonchik/tgmanager"
+)
+
+// User presses buttons and types messages in a chat like a telegram user would.
+type User struct {
+	t       testing.TB
+	manager tgmanager.CallbackManager
+	sender  *Sender
+
+	Actor  tgmanager.Actor
+	ChatID int64
+
+	queries int
+}
+
+// NewUser creates a user chatting with the bot in a private chat with the user id as chat id.
+func NewUser(t testing.TB, manager tgmanager.CallbackManager, sender *Sender, userID int64) *User {
+	return &User{
+		t:       t,
+		manager: manager,
+		sender:  sender,
+		Actor:   tgmanager.Actor{UserID: userID, ChatType: tgmanager.ChatTypePrivate},
+		ChatID:  userID,
+	}
+}
+
+func (u *User) context() context.Context {
+	return tgmanager.WithActor(context.Background(), u.Actor)
+}
+
+// Start sends the node of the processor to the user, like a /start command handler would.
+func (u *User) Start(processor string) error {
+	return u.manager.SendNode(u.context(), tgmanager.NewInOutData(u.ChatID, 0, "", tgmanager.CallBackAppearTypeResend), processor)
+}
+
+// Press presses the button with the label on the current message. Switch inline buttons
+// type their query, link buttons can't be pressed.
+func (u *User) Press(label string) error {
+	msg, ok := u.current()
+	if !ok {
+		return fmt.Errorf("no message to press %q on", label)
+	}
+	for _, button := range msg.Container.Buttons {
+		if button.ButtonLabel != label {
+			continue
+		}
+		switch {
+		case button.Callback != "":
+			return u.callback(msg.ID, button.Callback)
+		case button.SwitchInlineQueryCurrentChat != nil:
+			return u.Type(button.SwitchInlineQueryCurrentChat.GetText())
+		default:
+			return fmt.Errorf("button %q is a link", label)
+		}
+	}
+	return fmt.Errorf("button %q not found, buttons: %v", label, u.Buttons())
+}
+
+// Back presses the back button of the current message.
+func (u *User) Back() error {
+	return u.pressType(tgmanager.CallbackProcessorTypeBack)
+}
+
+// Close presses the close button of the current message.
+func (u *User) Close() error {
+	return u.pressType(tgmanager.CallbackProcessorTypeClose)
+}
+
+// Type sends a text message to the bot.
+func (u *User) Type(text string) error {
+	msgID := u.sender.receive(u.ChatID, text)
+	return u.manager.ProcessMsg(u.context(), u.Actor, msgID, u.ChatID, text)
+}
+
+func (u *User) pressType(processorType tgmanager.CallbackProcessorType) error {
+	msg, ok := u.current()
+	if !ok {
+		return fmt.Errorf("no message to press %s on", processorType)
+	}
+	for _, button := range msg.Container.Buttons {
+		if button.Callback != "" && button.ProcessorType == processorType {
+			return u.callback(msg.ID, button.Callback)
+		}
+	}
+	return fmt.Errorf("no %s button, buttons: %v", processorType, u.Buttons())
+}
+
+func (u *User) callback(msgID int64, callback string) error {
+	u.queries++
+	return u.manager.ProcessCallback(u.context(), u.Actor, strconv.Itoa(u.queries), msgID, u.ChatID, callback)
+}
+
+// current returns the latest bot message with a keyboard, or the latest bot message when none has one.
+func (u *User) current() (Message, bool) {
+	var messages []Message
+	for _, msg := range u.sender.Messages(u.ChatID) {
+		if !msg.FromUser {
+			messages = append(messages, msg)
+		}
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if len(messages[i].Container.Buttons) != 0 {
+			return messages[i], true
+		}
+	}
+	if len(messages) == 0 {
+		return Message{}, false
+	}
+	return messages[len(messages)-1], true
+}
+
+// Current returns the container of the message the user looks at.
+func (u *User) Current() tgmanager.TelegramContainer {
+	msg, _ := u.current()
+	return msg.Container
+}
+
+// Text returns the text of the current message.
+func (u *User) Text() string {
+	return u.Current().Message
+}
+
+// Buttons returns the labels of the current message buttons.
+func (u *User) Buttons() []string {
+	var labels []string
+	for _, button := range u.Current().Buttons {
+		labels = append(labels, button.ButtonLabel)
+	}
+	return labels
+}
+
+// AssertText fails the test when the current message text differs.
+func (u *User) AssertText(expected string) {
+	u.t.Helper()
+	if actual := u.Text(); actual != expected {
+		u.t.Errorf("message text non match\nactual:   %q\nexpected: %q", actual, expected)
+	}
+}
+
+// AssertButtons fails the test when the current message buttons differ.
+func (u *User) AssertButtons(expected ...string) {
+	u.t.Helper()
+	if actual := u.Buttons(); !reflect.DeepEqual(actual, expected) && (len(actual) != 0 || len(expected) != 0) {
+		u.t.Errorf("buttons non match\nactual:   %q\nexpected: %q", actual, expected)
+	}
+}
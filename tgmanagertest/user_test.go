@@ -0,0 +1,210 @@
+package tgmanagertest
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/tarmalonchik/tgmanager"
+)
+
+func newTestManager(t *testing.T, appearType tgmanager.CallBackAppearType) (tgmanager.CallbackManager, *Sender, *Storage) {
+	sender, storage := NewSender(), NewStorage()
+	manager, err := tgmanager.NewCallbackManager("default", appearType, nil, storage, sender, nil, nil, nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	t.Cleanup(func() { _ = manager.Close(context.Background()) })
+	err = manager.AddProcessors(tgmanager.Processor{
+		Name: "menu",
+		Processor: func(_ context.Context, data tgmanager.InOutData) (tgmanager.InOutData, error) {
+			data.SetMsg("Welcome")
+			data.AddNode(tgmanager.NewDefaultNode("Catalog", "catalog", tgmanager.CallbackProcessorTypeProcess, nil))
+			data.AddNode(tgmanager.NewDefaultNode("Close", "", tgmanager.CallbackProcessorTypeClose, nil))
+			return data, nil
+		},
+	}, tgmanager.Processor{
+		Name: "catalog",
+		Processor: func(_ context.Context, data tgmanager.InOutData) (tgmanager.InOutData, error) {
+			data.SetMsg("Catalog")
+			data.AddNode(tgmanager.NewDefaultNode("Buy", "bought", tgmanager.CallbackProcessorTypeProcess, []byte("42")))
+			data.AddNode(tgmanager.NewDefaultNode("Back", "menu", tgmanager.CallbackProcessorTypeBack, nil))
+			return data, nil
+		},
+	}, tgmanager.Processor{
+		Name: "bought",
+		Processor: func(_ context.Context, data tgmanager.InOutData) (tgmanager.InOutData, error) {
+			data.SetMsg("Bought " + string(data.GetPayload()))
+			return data, nil
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	return manager, sender, storage
+}
+
+func Test_User(t *testing.T) {
+	manager, sender, storage := newTestManager(t, tgmanager.CallBackAppearTypeUpdate)
+	user := NewUser(t, manager, sender, 7)
+
+	for _, step := range []struct {
+		name    string
+		action  func() error
+		text    string
+		buttons []string
+	}{
+		{name: "start", action: func() error { return user.Start("menu") }, text: "Welcome", buttons: []string{"Catalog", "Close"}},
+		{name: "catalog", action: func() error { return user.Press("Catalog") }, text: "Catalog", buttons: []string{"Buy", "Back"}},
+		{name: "back", action: user.Back, text: "Welcome", buttons: []string{"Catalog", "Close"}},
+		{name: "buy", action: func() error {
+			if err := user.Press("Catalog"); err != nil {
+				return err
+			}
+			return user.Press("Buy")
+		}, text: "Bought 42"},
+	} {
+		if err := step.action(); err != nil {
+			t.Fatal(step.name, "unexpected error:", err)
+		}
+		user.AssertText(step.text)
+		user.AssertButtons(step.buttons...)
+	}
+
+	if err := user.Press("Missing"); err == nil {
+		t.Error("expected missing button error")
+	}
+	if len(sender.Messages(user.ChatID)) != 1 || storage.Len() != 1 {
+		t.Error("menu must be edited in place", "messages:", len(sender.Messages(user.ChatID)), "states:", storage.Len())
+	}
+
+	if err := user.Start("menu"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := user.Close(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := manager.Close(context.Background()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(sender.Messages(user.ChatID)) != 1 || storage.Len() != 1 {
+		t.Error("closed menu must be deleted", "messages:", len(sender.Messages(user.ChatID)), "states:", storage.Len())
+	}
+}
+
+func Test_User_snapshots(t *testing.T) {
+	manager, sender, _ := newTestManager(t, tgmanager.CallBackAppearTypeUpdate)
+	user := NewUser(t, manager, sender, 7)
+
+	if err := user.Start("menu"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	user.AssertSnapshot("menu")
+	if err := user.Press("Catalog"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	user.AssertSnapshot("catalog")
+}
+
+func Test_User_Type(t *testing.T) {
+	manager, sender, _ := newTestManager(t, tgmanager.CallBackAppearTypeUpdate)
+	user := NewUser(t, manager, sender, 7)
+
+	if err := user.Start("menu"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	// no inline processors are registered, the message is recorded anyway
+	_ = user.Type("hello")
+	if err := user.Start("menu"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	messages := sender.Messages(user.ChatID)
+	for j, expected := range []struct {
+		id       int64
+		fromUser bool
+		text     string
+	}{
+		{id: 1, text: "Welcome"},
+		{id: 2, fromUser: true, text: "hello"},
+		{id: 3, text: "Welcome"},
+	} {
+		if j >= len(messages) {
+			t.Fatal("missing message", j, "messages:", messages)
+		}
+		if msg := messages[j]; msg.ID != expected.id || msg.FromUser != expected.fromUser || msg.Container.Message != expected.text {
+			t.Error("message", j, "non match:", msg)
+		}
+	}
+	user.AssertButtons("Catalog", "Close")
+}
+
+func Test_User_resendDeleteOld(t *testing.T) {
+	manager, sender, storage := newTestManager(t, tgmanager.CallBackAppearTypeResendDeleteOld)
+	user := NewUser(t, manager, sender, 7)
+
+	for _, step := range []struct {
+		name   string
+		action func() error
+		text   string
+	}{
+		{name: "start", action: func() error { return user.Start("menu") }, text: "Welcome"},
+		{name: "catalog", action: func() error { return user.Press("Catalog") }, text: "Catalog"},
+		{name: "back", action: user.Back, text: "Welcome"},
+	} {
+		if err := step.action(); err != nil {
+			t.Fatal(step.name, "unexpected error:", err)
+		}
+		user.AssertText(step.text)
+		if messages := sender.Messages(user.ChatID); len(messages) != 1 {
+			t.Error(step.name, "old message must be replaced, messages:", len(messages))
+		}
+	}
+
+	// old states are deleted in the background
+	if err := manager.Close(context.Background()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if storage.Len() != 1 {
+		t.Error("old states must be deleted, states:", storage.Len())
+	}
+}
+
+// failTB records failures and stops the goroutine on Fatal like testing does.
+type failTB struct {
+	testing.TB
+	failed bool
+}
+
+func (f *failTB) Helper() {}
+
+func (f *failTB) Fatalf(string, ...any) {
+	f.failed = true
+	runtime.Goexit()
+}
+
+func (f *failTB) Errorf(string, ...any) {
+	f.failed = true
+}
+
+func Test_AssertGolden_missing(t *testing.T) {
+	t.Setenv(UpdateGoldenEnv, "")
+
+	tb := &failTB{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		AssertGolden(tb, "missing", "text")
+	}()
+	<-done
+	if !tb.failed {
+		t.Error("missing golden file must fail the test")
+	}
+	if _, err := os.Stat(filepath.Join("testdata", "missing.golden")); !errors.Is(err, fs.ErrNotExist) {
+		t.Error("missing golden file must not be written")
+	}
+}
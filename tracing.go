@@ -0,0 +1,89 @@
+package tgmanager
+
+import (
+	"context"
+	"time"
+)
+
+// Span attribute keys set by the manager.
+const (
+	AttrChatID       = "tgmanager.chat_id"
+	AttrMessageID    = "tgmanager.message_id"
+	AttrProcessor    = "tgmanager.processor"
+	AttrCallbackType = "tgmanager.callback_type"
+	AttrAppearType   = "tgmanager.appear_type"
+	AttrOperation    = "tgmanager.operation"
+)
+
+// Attribute is a span attribute, Value is a string, an int64 or a bool.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+func StringAttribute(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+func Int64Attribute(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Tracer starts spans, it follows the OpenTelemetry tracer shape: the returned context carries
+// the span, so spans started by processors from their ctx become children of the manager spans.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is ended once, errors are recorded before End.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) End()                       {}
+
+// SetTracer enables tracing of callbacks, messages and sent nodes with processor,
+// storage and sender sub-spans, nil disables it.
+func (c *callbackManager) SetTracer(tracer Tracer) {
+	if tracer == nil {
+		tracer = noopTracer{}
+	}
+	c.tracer = tracer
+}
+
+func (c *callbackManager) startSpan(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	if c.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return c.tracer.Start(ctx, name, attrs...)
+}
+
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// instrument starts a storage or sender sub-span and returns the function finishing it
+// together with the latency metric.
+func (c *callbackManager) instrument(ctx context.Context, metric, component, op string, attrs ...Attribute) (context.Context, func(err error)) {
+	start := time.Now()
+	ctx, span := c.startSpan(ctx, "tgmanager."+component+"."+op, append(attrs, StringAttribute(AttrOperation, op))...)
+	return ctx, func(err error) {
+		c.observeSince(metric, start, Labels{metricLabelOperation: op})
+		endSpan(span, err)
+	}
+}
@@ -0,0 +1,81 @@
+package tgmanager
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+type testSpanKey struct{}
+
+type testSpan struct {
+	name   string
+	parent string
+	attrs  map[string]any
+	err    error
+}
+
+func (s *testSpan) SetAttributes(attrs ...Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *testSpan) RecordError(err error) { s.err = err }
+func (s *testSpan) End()                  {}
+
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	span := &testSpan{name: name, attrs: make(map[string]any)}
+	if parent, ok := ctx.Value(testSpanKey{}).(*testSpan); ok {
+		span.parent = parent.name
+	}
+	span.SetAttributes(attrs...)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+func Test_tracing(t *testing.T) {
+	tracer := &testTracer{}
+	manager := newTestConsistencyManager(t, &testStorage{}, &testDeleteSender{})
+	manager.SetTracer(tracer)
+
+	ctx := context.Background()
+	if err := manager.SendNode(ctx, NewInOutData(1, 0, "hello", CallBackAppearTypeResend), "start"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := manager.ProcessCallback(ctx, Actor{}, "", 1, 1, "start>0>0"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	parents := make(map[string]string)
+	var root *testSpan
+	for _, span := range tracer.spans {
+		parents[span.name] = span.parent
+		if span.name == "tgmanager.ProcessCallback" {
+			root = span
+		}
+	}
+	for name, parent := range map[string]string{
+		"tgmanager.ProcessCallback":     "",
+		"tgmanager.storage.get":         "tgmanager.ProcessCallback",
+		"tgmanager.processor":           "tgmanager.ProcessCallback",
+		"tgmanager.sender.send_message": "tgmanager.ProcessCallback",
+		"tgmanager.storage.save":        "tgmanager.ProcessCallback",
+	} {
+		if actual, ok := parents[name]; !ok || actual != parent {
+			t.Error("span parent non match", "span:", name, "actual:", actual, "expected:", parent)
+		}
+	}
+	if root == nil || root.attrs[AttrProcessor] != "start" || root.attrs[AttrCallbackType] != "process" ||
+		root.attrs[AttrAppearType] != CallBackAppearTypeUpdate.String() {
+		t.Error("unexpected root span attributes", root)
+	}
+}
@@ -7,6 +7,26 @@ import (
 
 var (
 	ErrMessageProcessorNotFound = errors.New("message processor not found")
+	ErrInvalidMarkup            = errors.New("invalid markup")
+	ErrNotMenuOwner             = errors.New("callback from a user who doesn't own the menu")
+	ErrProcessorForbidden       = errors.New("processor is forbidden for the user")
+	ErrRateLimited              = errors.New("action rate limited")
+	ErrSenderClosed             = errors.New("sender is closed")
+	ErrCircuitOpen              = errors.New("sender circuit is open")
+	ErrManagerClosed            = errors.New("callback manager is closed")
+
+	ErrInvalidCallback   = errors.New("invalid callback")
+	ErrProcessorNotFound = errors.New("processor not found")
+	ErrStateNotFound     = errors.New("state not found")
+	ErrStateCorrupt      = errors.New("state is corrupt")
+	ErrStaleIndex        = errors.New("callback index is stale")
+	ErrInvalidNode       = errors.New("invalid next node")
+	ErrInvalidData       = errors.New("invalid data")
+	ErrStorage           = errors.New("storage failure")
+	ErrSendFailed        = errors.New("send failed")
+	ErrProcessorPanic    = errors.New("processor panicked")
+	ErrProcessorTimeout  = errors.New("processor timed out")
+	ErrInvalidGraph      = errors.New("invalid processor graph")
 )
 
 // CallBackAppearType ENUM(update,resend,resend_delete_old)
@@ -14,3 +34,33 @@ type CallBackAppearType string
 
 // CallbackProcessorType ENUM(process,back,close,skip,ignore)
 type CallbackProcessorType int
+
+// ParseMode ENUM(html=HTML,markdown_v2=MarkdownV2)
+type ParseMode string
+
+// MediaType ENUM(photo,document,video)
+type MediaType string
+
+// ChatType ENUM(private,group,supergroup,channel)
+type ChatType string
+
+// NodeAccess ENUM(owner,shared)
+type NodeAccess string
+
+// ThrottleResponse ENUM(drop,toast,message)
+type ThrottleResponse string
+
+// SendPriority ENUM(interactive,bulk)
+type SendPriority int
+
+// SendErrorClass ENUM(retryable,rate_limited,permanent,unreachable)
+type SendErrorClass string
+
+// ConsistencyStrategy ENUM(compensate,outbox)
+type ConsistencyStrategy string
+
+// TransitionOutcome ENUM(success,closed,rejected,failed)
+type TransitionOutcome string
+
+// ValidationIssueKind ENUM(missing_target,duplicate,orphan)
+type ValidationIssueKind string
@@ -117,3 +117,424 @@ func ParseCallbackProcessorType(name string) (CallbackProcessorType, error) {
 	}
 	return CallbackProcessorType(0), fmt.Errorf("%s is %w", name, ErrInvalidCallbackProcessorType)
 }
+
+const (
+	// ParseModeHtml is a ParseMode of type html.
+	ParseModeHtml ParseMode = "HTML"
+	// ParseModeMarkdownV2 is a ParseMode of type markdown_v2.
+	ParseModeMarkdownV2 ParseMode = "MarkdownV2"
+)
+
+var ErrInvalidParseMode = errors.New("not a valid ParseMode")
+
+// String implements the Stringer interface.
+func (x ParseMode) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x ParseMode) IsValid() bool {
+	_, err := ParseParseMode(string(x))
+	return err == nil
+}
+
+var _ParseModeValue = map[string]ParseMode{
+	"HTML":       ParseModeHtml,
+	"html":       ParseModeHtml,
+	"MarkdownV2": ParseModeMarkdownV2,
+	"markdownv2": ParseModeMarkdownV2,
+}
+
+// ParseParseMode attempts to convert a string to a ParseMode.
+func ParseParseMode(name string) (ParseMode, error) {
+	if x, ok := _ParseModeValue[name]; ok {
+		return x, nil
+	}
+	// Case insensitive parse, do a separate lookup to prevent unnecessary cost of lowercasing a string if we don't need to.
+	if x, ok := _ParseModeValue[strings.ToLower(name)]; ok {
+		return x, nil
+	}
+	return ParseMode(""), fmt.Errorf("%s is %w", name, ErrInvalidParseMode)
+}
+
+const (
+	// MediaTypePhoto is a MediaType of type photo.
+	MediaTypePhoto MediaType = "photo"
+	// MediaTypeDocument is a MediaType of type document.
+	MediaTypeDocument MediaType = "document"
+	// MediaTypeVideo is a MediaType of type video.
+	MediaTypeVideo MediaType = "video"
+)
+
+var ErrInvalidMediaType = errors.New("not a valid MediaType")
+
+// String implements the Stringer interface.
+func (x MediaType) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x MediaType) IsValid() bool {
+	_, err := ParseMediaType(string(x))
+	return err == nil
+}
+
+var _MediaTypeValue = map[string]MediaType{
+	"photo":    MediaTypePhoto,
+	"document": MediaTypeDocument,
+	"video":    MediaTypeVideo,
+}
+
+// ParseMediaType attempts to convert a string to a MediaType.
+func ParseMediaType(name string) (MediaType, error) {
+	if x, ok := _MediaTypeValue[name]; ok {
+		return x, nil
+	}
+	// Case insensitive parse, do a separate lookup to prevent unnecessary cost of lowercasing a string if we don't need to.
+	if x, ok := _MediaTypeValue[strings.ToLower(name)]; ok {
+		return x, nil
+	}
+	return MediaType(""), fmt.Errorf("%s is %w", name, ErrInvalidMediaType)
+}
+
+const (
+	// ChatTypePrivate is a ChatType of type private.
+	ChatTypePrivate ChatType = "private"
+	// ChatTypeGroup is a ChatType of type group.
+	ChatTypeGroup ChatType = "group"
+	// ChatTypeSupergroup is a ChatType of type supergroup.
+	ChatTypeSupergroup ChatType = "supergroup"
+	// ChatTypeChannel is a ChatType of type channel.
+	ChatTypeChannel ChatType = "channel"
+)
+
+var ErrInvalidChatType = errors.New("not a valid ChatType")
+
+// String implements the Stringer interface.
+func (x ChatType) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x ChatType) IsValid() bool {
+	_, err := ParseChatType(string(x))
+	return err == nil
+}
+
+var _ChatTypeValue = map[string]ChatType{
+	"private":    ChatTypePrivate,
+	"group":      ChatTypeGroup,
+	"supergroup": ChatTypeSupergroup,
+	"channel":    ChatTypeChannel,
+}
+
+// ParseChatType attempts to convert a string to a ChatType.
+func ParseChatType(name string) (ChatType, error) {
+	if x, ok := _ChatTypeValue[name]; ok {
+		return x, nil
+	}
+	// Case insensitive parse, do a separate lookup to prevent unnecessary cost of lowercasing a string if we don't need to.
+	if x, ok := _ChatTypeValue[strings.ToLower(name)]; ok {
+		return x, nil
+	}
+	return ChatType(""), fmt.Errorf("%s is %w", name, ErrInvalidChatType)
+}
+
+const (
+	// NodeAccessOwner is a NodeAccess of type owner.
+	NodeAccessOwner NodeAccess = "owner"
+	// NodeAccessShared is a NodeAccess of type shared.
+	NodeAccessShared NodeAccess = "shared"
+)
+
+var ErrInvalidNodeAccess = errors.New("not a valid NodeAccess")
+
+// String implements the Stringer interface.
+func (x NodeAccess) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x NodeAccess) IsValid() bool {
+	_, err := ParseNodeAccess(string(x))
+	return err == nil
+}
+
+var _NodeAccessValue = map[string]NodeAccess{
+	"owner":  NodeAccessOwner,
+	"shared": NodeAccessShared,
+}
+
+// ParseNodeAccess attempts to convert a string to a NodeAccess.
+func ParseNodeAccess(name string) (NodeAccess, error) {
+	if x, ok := _NodeAccessValue[name]; ok {
+		return x, nil
+	}
+	// Case insensitive parse, do a separate lookup to prevent unnecessary cost of lowercasing a string if we don't need to.
+	if x, ok := _NodeAccessValue[strings.ToLower(name)]; ok {
+		return x, nil
+	}
+	return NodeAccess(""), fmt.Errorf("%s is %w", name, ErrInvalidNodeAccess)
+}
+
+const (
+	// ThrottleResponseDrop is a ThrottleResponse of type drop.
+	ThrottleResponseDrop ThrottleResponse = "drop"
+	// ThrottleResponseToast is a ThrottleResponse of type toast.
+	ThrottleResponseToast ThrottleResponse = "toast"
+	// ThrottleResponseMessage is a ThrottleResponse of type message.
+	ThrottleResponseMessage ThrottleResponse = "message"
+)
+
+var ErrInvalidThrottleResponse = errors.New("not a valid ThrottleResponse")
+
+// String implements the Stringer interface.
+func (x ThrottleResponse) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x ThrottleResponse) IsValid() bool {
+	_, err := ParseThrottleResponse(string(x))
+	return err == nil
+}
+
+var _ThrottleResponseValue = map[string]ThrottleResponse{
+	"drop":    ThrottleResponseDrop,
+	"toast":   ThrottleResponseToast,
+	"message": ThrottleResponseMessage,
+}
+
+// ParseThrottleResponse attempts to convert a string to a ThrottleResponse.
+func ParseThrottleResponse(name string) (ThrottleResponse, error) {
+	if x, ok := _ThrottleResponseValue[name]; ok {
+		return x, nil
+	}
+	// Case insensitive parse, do a separate lookup to prevent unnecessary cost of lowercasing a string if we don't need to.
+	if x, ok := _ThrottleResponseValue[strings.ToLower(name)]; ok {
+		return x, nil
+	}
+	return ThrottleResponse(""), fmt.Errorf("%s is %w", name, ErrInvalidThrottleResponse)
+}
+
+const (
+	// SendPriorityInteractive is a SendPriority of type Interactive.
+	SendPriorityInteractive SendPriority = iota
+	// SendPriorityBulk is a SendPriority of type Bulk.
+	SendPriorityBulk
+)
+
+var ErrInvalidSendPriority = errors.New("not a valid SendPriority")
+
+const _SendPriorityName = "interactivebulk"
+
+var _SendPriorityMap = map[SendPriority]string{
+	SendPriorityInteractive: _SendPriorityName[0:11],
+	SendPriorityBulk:        _SendPriorityName[11:15],
+}
+
+// String implements the Stringer interface.
+func (x SendPriority) String() string {
+	if str, ok := _SendPriorityMap[x]; ok {
+		return str
+	}
+	return fmt.Sprintf("SendPriority(%d)", x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x SendPriority) IsValid() bool {
+	_, ok := _SendPriorityMap[x]
+	return ok
+}
+
+var _SendPriorityValue = map[string]SendPriority{
+	_SendPriorityName[0:11]:                   SendPriorityInteractive,
+	strings.ToLower(_SendPriorityName[0:11]):  SendPriorityInteractive,
+	_SendPriorityName[11:15]:                  SendPriorityBulk,
+	strings.ToLower(_SendPriorityName[11:15]): SendPriorityBulk,
+}
+
+// ParseSendPriority attempts to convert a string to a SendPriority.
+func ParseSendPriority(name string) (SendPriority, error) {
+	if x, ok := _SendPriorityValue[name]; ok {
+		return x, nil
+	}
+	// Case insensitive parse, do a separate lookup to prevent unnecessary cost of lowercasing a string if we don't need to.
+	if x, ok := _SendPriorityValue[strings.ToLower(name)]; ok {
+		return x, nil
+	}
+	return SendPriority(0), fmt.Errorf("%s is %w", name, ErrInvalidSendPriority)
+}
+
+const (
+	// SendErrorClassRetryable is a SendErrorClass of type retryable.
+	SendErrorClassRetryable SendErrorClass = "retryable"
+	// SendErrorClassRateLimited is a SendErrorClass of type rate_limited.
+	SendErrorClassRateLimited SendErrorClass = "rate_limited"
+	// SendErrorClassPermanent is a SendErrorClass of type permanent.
+	SendErrorClassPermanent SendErrorClass = "permanent"
+	// SendErrorClassUnreachable is a SendErrorClass of type unreachable.
+	SendErrorClassUnreachable SendErrorClass = "unreachable"
+)
+
+var ErrInvalidSendErrorClass = errors.New("not a valid SendErrorClass")
+
+// String implements the Stringer interface.
+func (x SendErrorClass) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x SendErrorClass) IsValid() bool {
+	_, err := ParseSendErrorClass(string(x))
+	return err == nil
+}
+
+var _SendErrorClassValue = map[string]SendErrorClass{
+	"retryable":    SendErrorClassRetryable,
+	"rate_limited": SendErrorClassRateLimited,
+	"permanent":    SendErrorClassPermanent,
+	"unreachable":  SendErrorClassUnreachable,
+}
+
+// ParseSendErrorClass attempts to convert a string to a SendErrorClass.
+func ParseSendErrorClass(name string) (SendErrorClass, error) {
+	if x, ok := _SendErrorClassValue[name]; ok {
+		return x, nil
+	}
+	// Case insensitive parse, do a separate lookup to prevent unnecessary cost of lowercasing a string if we don't need to.
+	if x, ok := _SendErrorClassValue[strings.ToLower(name)]; ok {
+		return x, nil
+	}
+	return SendErrorClass(""), fmt.Errorf("%s is %w", name, ErrInvalidSendErrorClass)
+}
+
+const (
+	// ConsistencyStrategyCompensate is a ConsistencyStrategy of type compensate.
+	ConsistencyStrategyCompensate ConsistencyStrategy = "compensate"
+	// ConsistencyStrategyOutbox is a ConsistencyStrategy of type outbox.
+	ConsistencyStrategyOutbox ConsistencyStrategy = "outbox"
+)
+
+var ErrInvalidConsistencyStrategy = errors.New("not a valid ConsistencyStrategy")
+
+// String implements the Stringer interface.
+func (x ConsistencyStrategy) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x ConsistencyStrategy) IsValid() bool {
+	_, err := ParseConsistencyStrategy(string(x))
+	return err == nil
+}
+
+var _ConsistencyStrategyValue = map[string]ConsistencyStrategy{
+	"compensate": ConsistencyStrategyCompensate,
+	"outbox":     ConsistencyStrategyOutbox,
+}
+
+// ParseConsistencyStrategy attempts to convert a string to a ConsistencyStrategy.
+func ParseConsistencyStrategy(name string) (ConsistencyStrategy, error) {
+	if x, ok := _ConsistencyStrategyValue[name]; ok {
+		return x, nil
+	}
+	// Case insensitive parse, do a separate lookup to prevent unnecessary cost of lowercasing a string if we don't need to.
+	if x, ok := _ConsistencyStrategyValue[strings.ToLower(name)]; ok {
+		return x, nil
+	}
+	return ConsistencyStrategy(""), fmt.Errorf("%s is %w", name, ErrInvalidConsistencyStrategy)
+}
+
+const (
+	// TransitionOutcomeSuccess is a TransitionOutcome of type success.
+	TransitionOutcomeSuccess TransitionOutcome = "success"
+	// TransitionOutcomeClosed is a TransitionOutcome of type closed.
+	TransitionOutcomeClosed TransitionOutcome = "closed"
+	// TransitionOutcomeRejected is a TransitionOutcome of type rejected.
+	TransitionOutcomeRejected TransitionOutcome = "rejected"
+	// TransitionOutcomeFailed is a TransitionOutcome of type failed.
+	TransitionOutcomeFailed TransitionOutcome = "failed"
+)
+
+var ErrInvalidTransitionOutcome = errors.New("not a valid TransitionOutcome")
+
+// String implements the Stringer interface.
+func (x TransitionOutcome) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x TransitionOutcome) IsValid() bool {
+	_, err := ParseTransitionOutcome(string(x))
+	return err == nil
+}
+
+var _TransitionOutcomeValue = map[string]TransitionOutcome{
+	"success":  TransitionOutcomeSuccess,
+	"closed":   TransitionOutcomeClosed,
+	"rejected": TransitionOutcomeRejected,
+	"failed":   TransitionOutcomeFailed,
+}
+
+// ParseTransitionOutcome attempts to convert a string to a TransitionOutcome.
+func ParseTransitionOutcome(name string) (TransitionOutcome, error) {
+	if x, ok := _TransitionOutcomeValue[name]; ok {
+		return x, nil
+	}
+	// Case insensitive parse, do a separate lookup to prevent unnecessary cost of lowercasing a string if we don't need to.
+	if x, ok := _TransitionOutcomeValue[strings.ToLower(name)]; ok {
+		return x, nil
+	}
+	return TransitionOutcome(""), fmt.Errorf("%s is %w", name, ErrInvalidTransitionOutcome)
+}
+
+const (
+	// ValidationIssueKindMissingTarget is a ValidationIssueKind of type missing_target.
+	ValidationIssueKindMissingTarget ValidationIssueKind = "missing_target"
+	// ValidationIssueKindDuplicate is a ValidationIssueKind of type duplicate.
+	ValidationIssueKindDuplicate ValidationIssueKind = "duplicate"
+	// ValidationIssueKindOrphan is a ValidationIssueKind of type orphan.
+	ValidationIssueKindOrphan ValidationIssueKind = "orphan"
+)
+
+var ErrInvalidValidationIssueKind = errors.New("not a valid ValidationIssueKind")
+
+// String implements the Stringer interface.
+func (x ValidationIssueKind) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x ValidationIssueKind) IsValid() bool {
+	_, err := ParseValidationIssueKind(string(x))
+	return err == nil
+}
+
+var _ValidationIssueKindValue = map[string]ValidationIssueKind{
+	"missing_target": ValidationIssueKindMissingTarget,
+	"duplicate":      ValidationIssueKindDuplicate,
+	"orphan":         ValidationIssueKindOrphan,
+}
+
+// ParseValidationIssueKind attempts to convert a string to a ValidationIssueKind.
+func ParseValidationIssueKind(name string) (ValidationIssueKind, error) {
+	if x, ok := _ValidationIssueKindValue[name]; ok {
+		return x, nil
+	}
+	// Case insensitive parse, do a separate lookup to prevent unnecessary cost of lowercasing a string if we don't need to.
+	if x, ok := _ValidationIssueKindValue[strings.ToLower(name)]; ok {
+		return x, nil
+	}
+	return ValidationIssueKind(""), fmt.Errorf("%s is %w", name, ErrInvalidValidationIssueKind)
+}
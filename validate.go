@@ -0,0 +1,103 @@
+package tgmanager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidationIssue is a problem of the processor graph. Target is set for missing targets.
+type ValidationIssue struct {
+	Kind      ValidationIssueKind
+	Processor string
+	Target    string
+}
+
+func (i ValidationIssue) String() string {
+	switch i.Kind {
+	case ValidationIssueKindMissingTarget:
+		return fmt.Sprintf("processor %s links to unknown processor %s", i.Processor, i.Target)
+	case ValidationIssueKindDuplicate:
+		return fmt.Sprintf("processor %s is registered more than once", i.Processor)
+	default:
+		return fmt.Sprintf("processor %s is not reachable", i.Processor)
+	}
+}
+
+// ValidationError lists all problems found by Validate.
+type ValidationError struct {
+	Issues []ValidationIssue
+}
+
+func (e *ValidationError) Error() string {
+	items := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		items = append(items, issue.String())
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidGraph, strings.Join(items, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidGraph
+}
+
+// Validate checks the processor graph before serving. Links are taken from Processor.Links and
+// from nodes recorded with Crawl or SetFlowRecording. Orphans, processors which are neither
+// entries nor link targets, are only reported when any links are known.
+func (c *callbackManager) Validate() error {
+	var issues []ValidationIssue
+	for _, name := range sortedKeys(c.duplicateProcessors) {
+		issues = append(issues, ValidationIssue{Kind: ValidationIssueKindDuplicate, Processor: name})
+	}
+	for _, name := range sortedKeys(c.inlineProcessorMap) {
+		if _, ok := c.allProcessors[name]; ok {
+			if _, reported := c.duplicateProcessors[name]; !reported {
+				issues = append(issues, ValidationIssue{Kind: ValidationIssueKindDuplicate, Processor: name})
+			}
+		}
+	}
+
+	type link struct{ from, to string }
+	links := make(map[link]struct{})
+	for from, targets := range c.processorLinks {
+		for _, to := range targets {
+			links[link{from: from, to: to}] = struct{}{}
+		}
+	}
+	graph := c.FlowGraph()
+	for _, edge := range graph.Edges {
+		links[link{from: edge.From, to: edge.To}] = struct{}{}
+	}
+
+	reachable := make(map[string]struct{})
+	for _, name := range append(sortedKeys(c.entryProcessors), graph.Entries...) {
+		reachable[name] = struct{}{}
+	}
+	var missing []ValidationIssue
+	for item := range links {
+		reachable[item.to] = struct{}{}
+		if _, ok := c.allProcessors[item.to]; !ok {
+			missing = append(missing, ValidationIssue{Kind: ValidationIssueKindMissingTarget, Processor: item.from, Target: item.to})
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].Processor != missing[j].Processor {
+			return missing[i].Processor < missing[j].Processor
+		}
+		return missing[i].Target < missing[j].Target
+	})
+	issues = append(issues, missing...)
+
+	if len(links) != 0 {
+		for _, name := range sortedKeys(c.allProcessors) {
+			if _, ok := reachable[name]; !ok {
+				issues = append(issues, ValidationIssue{Kind: ValidationIssueKindOrphan, Processor: name})
+			}
+		}
+	}
+
+	if len(issues) == 0 {
+		return nil
+	}
+	return &ValidationError{Issues: issues}
+}
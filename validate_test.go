@@ -0,0 +1,62 @@
+package tgmanager
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_Validate(t *testing.T) {
+	noop := func(_ context.Context, data InOutData) (InOutData, error) { return data, nil }
+
+	manager, err := NewCallbackManager("default", CallBackAppearTypeUpdate, nil, &testStorage{}, &testDeleteSender{}, nil, nil, nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	t.Cleanup(func() { _ = manager.Close(context.Background()) })
+	if err = manager.Validate(); err != nil {
+		t.Fatal("empty manager must be valid:", err)
+	}
+
+	err = manager.AddProcessors(
+		Processor{Name: "menu", Processor: noop, Entry: true, Links: []string{"catalog", "catlog"}},
+		Processor{Name: "catalog", Processor: noop, Links: []string{"menu"}},
+		Processor{Name: "search", Processor: noop},
+		Processor{Name: "legacy", Processor: noop},
+	)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	err = manager.AddInlineProcessors(InlineProcessor{Name: "search"})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err = manager.Validate()
+	var validationErr *ValidationError
+	if !errors.Is(err, ErrInvalidGraph) || !errors.As(err, &validationErr) {
+		t.Fatal("expected validation error, got", err)
+	}
+	expected := []ValidationIssue{
+		{Kind: ValidationIssueKindDuplicate, Processor: "search"},
+		{Kind: ValidationIssueKindMissingTarget, Processor: "menu", Target: "catlog"},
+		{Kind: ValidationIssueKindOrphan, Processor: "legacy"},
+		{Kind: ValidationIssueKindOrphan, Processor: "search"},
+	}
+	if !reflect.DeepEqual(validationErr.Issues, expected) {
+		t.Error("issues non match", "actual:", validationErr.Issues, "expected:", expected)
+	}
+}
+
+func Test_Validate_crawl(t *testing.T) {
+	manager := newTestFlowManager(t)
+	if err := manager.Crawl(context.Background(), "menu"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	var validationErr *ValidationError
+	if err := manager.Validate(); !errors.As(err, &validationErr) ||
+		!reflect.DeepEqual(validationErr.Issues, []ValidationIssue{{Kind: ValidationIssueKindOrphan, Processor: "legacy"}}) {
+		t.Error("expected legacy orphan, got", err)
+	}
+}